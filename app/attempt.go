@@ -0,0 +1,32 @@
+package app
+
+import "context"
+
+/*
+Attempt of job execution is passed by queue in context,
+so job can tell the last attempt, e.g. to record failure which won't be retried.
+*/
+
+type jobAttemptKey struct{}
+
+type JobAttempt struct {
+	//0 for the first attempt
+	Retried  int
+	MaxRetry int
+}
+
+func WithJobAttempt(ctx context.Context, retried int, maxRetry int) context.Context {
+	return context.WithValue(ctx, jobAttemptKey{}, &JobAttempt{Retried: retried, MaxRetry: maxRetry})
+}
+
+// nil if job isn't executed by queue
+func GetJobAttempt(ctx context.Context) *JobAttempt {
+	attempt, _ := ctx.Value(jobAttemptKey{}).(*JobAttempt)
+	return attempt
+}
+
+// job executed without queue isn't retried, so its attempt is the last one
+func IsLastJobAttempt(ctx context.Context) bool {
+	attempt := GetJobAttempt(ctx)
+	return attempt == nil || attempt.Retried >= attempt.MaxRetry
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+)
+
+/*
+Calls of items found in containers, e.g. contract call transactions of block.
+They are fetched by secondary pass of container processing if Providers.<key>.TrackCalls is enabled,
+calls are stored in own collection and counted in CallStats of called items.
+*/
+
+type ItemCall struct {
+	//called item
+	Item *ItemId `bson:",inline"`
+	//container number, e.g. block
+	Seq  uint   `bson:"seq"`
+	Txid string `bson:"txid"`
+	//called method, e.g. transition
+	Tag     string `bson:"tag"`
+	Sender  string `bson:"sender"`
+	Success bool   `bson:"success"`
+	Gas     uint64 `bson:"gas"`
+	//decimal string in the smallest units, it may not fit uint64
+	Amount string `bson:"amount"`
+}
+
+// aggregated calls of item, see ICallRepository
+type CallStats struct {
+	Total   uint `bson:"total"`
+	Failed  uint `bson:"failed"`
+	Callers uint `bson:"callers"`
+	//the latest container with calls
+	LastSeq uint `bson:"lastseq"`
+}
+
+// provider which finds calls of items, in addition to items
+type IActivityProvider interface {
+	FetchContainerCalls(ctx context.Context, container *ItemsContainer) ([]*ItemCall, error)
+}
+
+type ICallRepository interface {
+	/*
+		Stores calls and updates CallStats of called items (property "calls"),
+		calls stored before are skipped, so container may be processed again.
+	*/
+	SaveCalls(ctx context.Context, calls []*ItemCall) error
+	//sets CallStats counted before to items, e.g. for items saved after their calls
+	UpdateCallStats(ctx context.Context, ids []*ItemId) error
+	Close() error
+}
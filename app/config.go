@@ -1,25 +1,79 @@
 package app
 
 import (
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
 type ItemProviderConfig map[string]interface{}
 
+// settings of follow mode, Providers.<key>.Follow in config.json
+type FollowConfig struct {
+	//pause between polls of provider
+	IntervalSec int
+	//max number of containers queued per poll
+	Limit uint
+	//don't queue more containers while queue holds this number of unfinished jobs, 0 means no limit
+	MaxQueueDepth int
+}
+
+/*
+Token bucket settings of provider, Providers.<key>.RateLimit in config.json.
+Limit is shared by all workers of provider through Redis (without Queue config the process is limited only).
+*/
+type RateLimitConfig struct {
+	RequestsPerSec float64
+	//requests allowed at once after idle time, RequestsPerSec rounded up by default
+	Burst int
+}
+
+const (
+	//delay is defined by queue implementation
+	BackoffDefault = "default"
+	//RetryDelaySec before each retry
+	BackoffFixed = "fixed"
+	//RetryDelaySec * 2^retried, but not more than RetryDelayMaxSec
+	BackoffExponential = "exponential"
+)
+
+// asynq default number of retries, it's used if MaxRetry isn't set
+const DefaultMaxRetry = 25
+
+/*
+Zero values mean "not set": defaults of queue implementation are used,
+or values of Queue.Job if it's override from Queue.Jobs.
+MaxRetry is pointer, because 0 (no retries) is a valid setting, nil means "not set".
+*/
 type JobConfig struct {
+	//weight of queue, workers take jobs from queues with greater priority more often
 	Priority   int
 	TimeoutSec int
-	MaxRetry   int
+	MaxRetry   *int
+	//see Backoff* constants
+	Backoff          string
+	RetryDelaySec    int
+	RetryDelayMaxSec int
 }
 
+/*
+Redis based queue of producer and worker commands.
+Commands which process jobs themselves (exec-*, crawl) use in-process memory queue, see memory.JobQueue.
+*/
 type QueueConfig struct {
 	Addr     string
 	User     string
 	Password string
-	Job      *JobConfig
+	//capacity of each queue of memory queue
+	MemorySize int
+	Job        *JobConfig
+	//overrides of Job by queue name, "*" at the end of name matches any suffix, e.g. "job:property:set:*"
+	Jobs map[string]*JobConfig
 }
 
 type StorageConfig struct {
@@ -48,24 +102,39 @@ func NewConfig(path ...string) (*AppConfig, error) {
 	if len(path) != 0 {
 		confPath = path[0]
 	}
-	viper.AddConfigPath(confPath)
-	viper.SetConfigName("config")
+	//own instance, so config of another path isn't shadowed by paths added before
+	v := viper.New()
+	v.AddConfigPath(confPath)
+	v.SetConfigName("config")
 
-	err := viper.ReadInConfig()
+	err := v.ReadInConfig()
 	if err != nil {
 		return nil, errors.Annotate(err, "can't read config.json")
 	}
 
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	err = viper.Unmarshal(&config)
+	err = v.Unmarshal(&config)
 	if err != nil {
 		return nil, errors.Annotate(err, "can't unmarshal config file")
 	} else if 0 == len(config.Providers) {
 		return nil, errors.New("provider(s) not found in config.json")
 	}
 
+	if config.Queue != nil {
+		if config.Queue.Job != nil {
+			if err := config.Queue.Job.Validate(); err != nil {
+				return nil, errors.Annotate(err, "invalid Queue.Job config")
+			}
+		}
+		for name, jconf := range config.Queue.Jobs {
+			if err := jconf.Validate(); err != nil {
+				return nil, errors.Annotatef(err, "invalid Queue.Jobs config, queue=%s", name)
+			}
+		}
+	}
+
 	return &config, nil
 }
 
@@ -74,5 +143,205 @@ func (conf *AppConfig) GetProviderConfigByKey(provKey string) (*ItemProviderConf
 	if pconf, found := conf.Providers[strings.ToLower(provKey)]; found {
 		return pconf, nil
 	}
-	return nil, errors.Errorf("provider config not found, key=%s", provKey)
+	return nil, errors.Errorf("provider config not found, key=%s, configured keys: %s",
+		provKey, strings.Join(conf.GetProviderKeys(), ", "))
+}
+
+func (conf *AppConfig) GetProviderKeys() []string {
+	result := make([]string, 0, len(conf.Providers))
+	for provKey := range conf.Providers {
+		result = append(result, provKey)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (conf *ItemProviderConfig) GetFollowConfig() (*FollowConfig, error) {
+	follow := &FollowConfig{
+		IntervalSec:   60,
+		Limit:         10,
+		MaxQueueDepth: 0,
+	}
+	// Keys in the config map are in lowercase, as Viper reads them
+	raw, found := (*conf)["follow"]
+	if !found {
+		return follow, nil
+	}
+	err := mapstructure.WeakDecode(raw, follow)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't decode follow config")
+	}
+	return follow, nil
+}
+
+// calls of items are fetched and stored by container jobs, see IActivityProvider
+func (conf *ItemProviderConfig) IsCallTrackingEnabled() (bool, error) {
+	// Keys in the config map are in lowercase, as Viper reads them
+	raw, found := (*conf)["trackcalls"]
+	if !found {
+		return false, nil
+	}
+	enabled := false
+	err := mapstructure.WeakDecode(raw, &enabled)
+	if err != nil {
+		return false, errors.Annotate(err, "can't decode TrackCalls")
+	}
+	return enabled, nil
+}
+
+// events of items are fetched and stored by container jobs, see IEventProvider
+func (conf *ItemProviderConfig) IsEventExtractionEnabled() (bool, error) {
+	// Keys in the config map are in lowercase, as Viper reads them
+	raw, found := (*conf)["extractevents"]
+	if !found {
+		return false, nil
+	}
+	enabled := false
+	err := mapstructure.WeakDecode(raw, &enabled)
+	if err != nil {
+		return false, errors.Annotate(err, "can't decode ExtractEvents")
+	}
+	return enabled, nil
+}
+
+// nil if rate limit isn't defined
+func (conf *ItemProviderConfig) GetRateLimitConfig() (*RateLimitConfig, error) {
+	// Keys in the config map are in lowercase, as Viper reads them
+	raw, found := (*conf)["ratelimit"]
+	if !found {
+		return nil, nil
+	}
+	rateLimit := &RateLimitConfig{}
+	err := mapstructure.WeakDecode(raw, rateLimit)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't decode rate limit config")
+	} else if rateLimit.RequestsPerSec <= 0 {
+		return nil, errors.New("RateLimit.RequestsPerSec must be greater than 0")
+	}
+	if rateLimit.Burst <= 0 {
+		rateLimit.Burst = int(math.Ceil(rateLimit.RequestsPerSec))
+	}
+	return rateLimit, nil
+}
+
+/*
+Job settings for queue: Queue.Job with override from Queue.Jobs applied.
+Exact queue name wins, then the longest matching pattern.
+*/
+func (conf *QueueConfig) GetJobConfig(queueName string) *JobConfig {
+	result := &JobConfig{}
+	if conf.Job != nil {
+		*result = *conf.Job
+	}
+
+	// Keys in the config map are in lowercase, as Viper reads them
+	queueName = strings.ToLower(queueName)
+	var override *JobConfig
+	matched := -1
+	for pattern, jconf := range conf.Jobs {
+		pattern = strings.ToLower(pattern)
+		if pattern == queueName {
+			override = jconf
+			break
+		} else if strings.HasSuffix(pattern, "*") {
+			prefix := strings.TrimSuffix(pattern, "*")
+			if strings.HasPrefix(queueName, prefix) && len(prefix) > matched {
+				override = jconf
+				matched = len(prefix)
+			}
+		}
+	}
+
+	if override != nil {
+		result.merge(override)
+	}
+	return result
+}
+
+func (jc *JobConfig) merge(override *JobConfig) {
+	if override.Priority != 0 {
+		jc.Priority = override.Priority
+	}
+	if override.TimeoutSec != 0 {
+		jc.TimeoutSec = override.TimeoutSec
+	}
+	if override.MaxRetry != nil {
+		jc.MaxRetry = override.MaxRetry
+	}
+	if override.Backoff != "" {
+		jc.Backoff = override.Backoff
+	}
+	if override.RetryDelaySec != 0 {
+		jc.RetryDelaySec = override.RetryDelaySec
+	}
+	if override.RetryDelayMaxSec != 0 {
+		jc.RetryDelayMaxSec = override.RetryDelayMaxSec
+	}
+}
+
+// false if MaxRetry isn't set
+func (jc *JobConfig) GetMaxRetry() (int, bool) {
+	if jc.MaxRetry == nil {
+		return 0, false
+	}
+	return *jc.MaxRetry, true
+}
+
+/*
+Delay before next attempt, retried is number of retries done already.
+Returns false if delay is up to queue implementation (default backoff).
+*/
+func (jc *JobConfig) RetryDelay(retried int) (time.Duration, bool) {
+	base := time.Duration(jc.RetryDelaySec) * time.Second
+	switch strings.ToLower(jc.Backoff) {
+	case BackoffFixed:
+		return base, true
+	case BackoffExponential:
+		delay := base
+		maxDelay := time.Duration(jc.RetryDelayMaxSec) * time.Second
+		for i := 0; i < retried; i++ {
+			delay *= 2
+			if maxDelay > 0 && delay >= maxDelay {
+				return maxDelay, true
+			}
+		}
+		return delay, true
+	default:
+		return 0, false
+	}
+}
+
+/*
+The longest delay before a retry of job, with MaxRetry of asynq if it isn't set.
+Default backoff is the asynq one: retried^4 + 15s + up to 30s*(retried+1).
+*/
+func (jc *JobConfig) MaxRetryDelay() time.Duration {
+	maxRetry, defined := jc.GetMaxRetry()
+	if !defined {
+		maxRetry = DefaultMaxRetry
+	}
+	if maxRetry <= 0 {
+		return 0
+	}
+	//delays grow with number of retries, so the last one is the longest
+	retried := maxRetry - 1
+	if delay, defined := jc.RetryDelay(retried); defined {
+		return delay
+	}
+	sec := retried*retried*retried*retried + 15 + 30*(retried+1)
+	return time.Duration(sec) * time.Second
+}
+
+func (jc *JobConfig) Validate() error {
+	switch strings.ToLower(jc.Backoff) {
+	case "", BackoffDefault:
+		return nil
+	case BackoffFixed, BackoffExponential:
+		if jc.RetryDelaySec <= 0 {
+			return errors.Errorf("RetryDelaySec must be greater than 0 for backoff=%s", jc.Backoff)
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown backoff: %s", jc.Backoff)
+	}
 }
@@ -15,6 +15,8 @@ type ItemsContainer struct {
 	For Zilliqa, the ID is the block number, one element in the array.
 	Possibly for other types of containers, there will be a composite ID.
 	*/
+	//data fetched by provider, it's shared by items, calls and events extraction of one job and isn't serialized
+	data map[string]interface{}
 }
 
 func NewItemsContainer(id []string) *ItemsContainer {
@@ -52,3 +54,16 @@ func (c *ItemsContainer) Uint() uint {
 func (c *ItemsContainer) SetId(id []string) {
 	c.Id = id
 }
+
+// data fetched for container before, e.g. transactions of block
+func (c *ItemsContainer) GetData(key string) (interface{}, bool) {
+	value, found := c.data[key]
+	return value, found
+}
+
+func (c *ItemsContainer) SetData(key string, value interface{}) {
+	if c.data == nil {
+		c.data = make(map[string]interface{})
+	}
+	c.data[key] = value
+}
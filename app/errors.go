@@ -0,0 +1,79 @@
+package app
+
+import (
+	"github.com/juju/errors"
+)
+
+/*
+Error taxonomy for provider calls.
+Providers attach kind to errors they return, queues use it to decide whether job should be retried.
+*/
+
+type ErrorKind int
+
+const (
+	//not classified, retried by queue, but not inside provider
+	ErrorKindUnknown ErrorKind = iota
+	//network problems, node is temporary unavailable (5xx), retry helps
+	ErrorKindTransient
+	//node limits request rate (429), retry later helps
+	ErrorKindRateLimited
+	//node has no requested data, e.g. block has no transactions
+	ErrorKindNotFound
+	//request is wrong or data is invalid, retry doesn't help
+	ErrorKindPermanent
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindTransient:
+		return "transient"
+	case ErrorKindRateLimited:
+		return "rate_limited"
+	case ErrorKindNotFound:
+		return "not_found"
+	case ErrorKindPermanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
+
+// retry of the same call may succeed
+func (k ErrorKind) IsRetryable() bool {
+	return k == ErrorKindTransient || k == ErrorKindRateLimited
+}
+
+type ClassifiedError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// kind is kept when error is annotated later (juju errors support Unwrap)
+func NewClassifiedError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Kind: kind, Err: err}
+}
+
+// the outermost kind attached to err, ErrorKindUnknown if there is no kind
+func GetErrorKind(err error) ErrorKind {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+	return ErrorKindUnknown
+}
+
+func IsPermanentError(err error) bool {
+	return GetErrorKind(err) == ErrorKindPermanent
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+)
+
+/*
+Events emitted by items in containers, e.g. event logs of transaction receipts of block.
+They are fetched by secondary pass of container processing if Providers.<key>.ExtractEvents is enabled.
+*/
+
+type ItemEvent struct {
+	//emitting item
+	Item *ItemId `bson:",inline"`
+	//container number, e.g. block
+	Seq  uint   `bson:"seq"`
+	Txid string `bson:"txid"`
+	//position of event in transaction
+	Index uint   `bson:"index"`
+	Name  string `bson:"name"`
+	//decoded params, name => value
+	Params map[string]interface{} `bson:"params"`
+}
+
+// provider which finds events of items, in addition to items
+type IEventProvider interface {
+	FetchContainerEvents(ctx context.Context, container *ItemsContainer) ([]*ItemEvent, error)
+}
+
+type IEventRepository interface {
+	// events stored before are skipped, so container may be processed again
+	SaveEvents(ctx context.Context, events []*ItemEvent) error
+	Close() error
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"reflect"
 	"time"
 
@@ -16,8 +17,8 @@ type Item struct {
 	ProvBranch          string
 	Id                  string
 	UpdatedAt           time.Time
-	realtimeAutosetters map[string]func() error
-	delayedAutosetters  map[string]func() error
+	realtimeAutosetters map[string]func(ctx context.Context) error
+	delayedAutosetters  map[string]func(ctx context.Context) error
 }
 
 type ItemId struct {
@@ -38,8 +39,8 @@ func NewItem(provName, provBranch, id string) *Item {
 		ProvBranch: provBranch,
 		Id:         id,
 		//UpdatedAt:           nil,
-		realtimeAutosetters: make(map[string]func() error, 0),
-		delayedAutosetters:  make(map[string]func() error, 0),
+		realtimeAutosetters: make(map[string]func(ctx context.Context) error, 0),
+		delayedAutosetters:  make(map[string]func(ctx context.Context) error, 0),
 	}
 	return item
 }
@@ -52,6 +53,14 @@ func (e *Item) GetId() *ItemId {
 	}
 }
 
+// converts id of item to canonical form if item supports several forms, see INormalizableItem
+func NormalizeItemId(item IItem) error {
+	if normalizable, ok := item.(INormalizableItem); ok {
+		return errors.Trace(normalizable.NormalizeId())
+	}
+	return nil
+}
+
 func (e *Item) GetProviderFilter() map[string]interface{} {
 	result := make(map[string]interface{}, 0)
 	result["provname"] = e.ProvName
@@ -72,19 +81,19 @@ func (e *Item) SetBaseField(fieldName string, fieldValue interface{}) error {
 	return nil
 }
 
-func (e *Item) RegisterRealtimeAutosetter(name string, p func() error) {
+func (e *Item) RegisterRealtimeAutosetter(name string, p func(ctx context.Context) error) {
 	e.realtimeAutosetters[name] = p
 }
 
-func (e *Item) RegisterDelayedAutosetter(name string, p func() error) {
+func (e *Item) RegisterDelayedAutosetter(name string, p func(ctx context.Context) error) {
 	e.delayedAutosetters[name] = p
 }
 
-func (e *Item) GetRealtimeAutosetters() map[string]func() error {
+func (e *Item) GetRealtimeAutosetters() map[string]func(ctx context.Context) error {
 	return e.realtimeAutosetters
 }
 
-func (e *Item) GetDelayedAutosetters() map[string]func() error {
+func (e *Item) GetDelayedAutosetters() map[string]func(ctx context.Context) error {
 	return e.delayedAutosetters
 }
 
@@ -92,25 +101,25 @@ func (e *Item) GetDelayedAutosetters() map[string]func() error {
 just call autosetter by its name
 autosetter's type doesn't matter in this method
 */
-func (e *Item) CallAutosetter(name string) error {
+func (e *Item) CallAutosetter(ctx context.Context, name string) error {
 	if function, found := e.realtimeAutosetters[name]; found {
-		return function()
+		return function(ctx)
 	} else if function, found := e.delayedAutosetters[name]; found {
-		return function()
+		return function(ctx)
 	}
 	return errors.Errorf("autosetter %s not found", name)
 }
 
-func (e *Item) CallRealtimeAutosetter(name string) error {
+func (e *Item) CallRealtimeAutosetter(ctx context.Context, name string) error {
 	if function, found := e.realtimeAutosetters[name]; found {
-		return function()
+		return function(ctx)
 	}
 	return errors.Errorf("autosetter %s not found", name)
 }
 
-func (e *Item) CallAllRealtimeAutosetters() {
+func (e *Item) CallAllRealtimeAutosetters(ctx context.Context) {
 	for _, function := range e.realtimeAutosetters {
-		function()
+		function(ctx)
 	}
 }
 
@@ -15,6 +15,11 @@ func (j *Job) GetDefaultQueueName() string {
 	return j.Name
 }
 
+// no deduplication by default
+func (j *Job) GetUniqueId() string {
+	return ""
+}
+
 func (j *Job) GetProviderKey() string {
 	return j.ProviderKey
 }
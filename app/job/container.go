@@ -1,7 +1,10 @@
 package job
 
 import (
+	"context"
 	"purrproof/smartcrawl/app"
+	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/oleiade/reflections"
@@ -10,11 +13,17 @@ import (
 
 const JobTypeContainerProcess = "job:container:process"
 
+// time to record failure in ledger
+const ledgerTimeout = 10 * time.Second
+
 var _ app.IJob = (*JobContainerProcess)(nil)
 
 type JobContainerProcess struct {
 	*app.Job
-	Container *app.ItemsContainer
+	Container       *app.ItemsContainer
+	ContainerLedger app.IContainerLedger `json:"-"` //optional, we don't need to store this object in a job
+	CallRepository  app.ICallRepository  `json:"-"` //optional, calls are tracked if it's set
+	EventRepository app.IEventRepository `json:"-"` //optional, events are extracted if it's set
 }
 
 /*
@@ -31,22 +40,84 @@ func NewMessageJobContainerProcess(provKey string, container *app.ItemsContainer
 	}
 }
 
-func (j *JobContainerProcess) Execute() ([]app.IJob, error) {
+// one job per container of provider
+func (j *JobContainerProcess) GetUniqueId() string {
+	if j.Container == nil {
+		return ""
+	}
+	return j.Name + ":" + strings.ToLower(j.ProviderKey) + ":" + j.Container.String()
+}
+
+func (j *JobContainerProcess) SetContainerLedger(ledger app.IContainerLedger) {
+	j.ContainerLedger = ledger
+}
+
+func (j *JobContainerProcess) SetCallRepository(repository app.ICallRepository) {
+	j.CallRepository = repository
+}
+
+func (j *JobContainerProcess) SetEventRepository(repository app.IEventRepository) {
+	j.EventRepository = repository
+}
+
+func (j *JobContainerProcess) Execute(ctx context.Context) ([]app.IJob, error) {
+
+	if j.ContainerLedger != nil && j.Container != nil {
+		err := j.ContainerLedger.MarkProcessing(ctx, j.ProviderKey, j.Container)
+		if err != nil {
+			return nil, errors.Annotate(err, "can't mark container as processing")
+		}
+	}
+
+	jobsOut, itemsCount, err := j.process(ctx)
+	if j.ContainerLedger == nil || j.Container == nil {
+		return jobsOut, err
+	}
+
+	if err != nil && !app.IsPermanentError(err) && !app.IsLastJobAttempt(ctx) {
+		//job is retried, container stays processing
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"container": j.Container.String(),
+		}).Debug("container isn't marked as failed, job will be retried")
+		return nil, err
+	} else if err != nil {
+		//job fails anyway, so only log ledger error
+		//job context may be done already (timeout, shutdown), but failure must be recorded
+		lctx, cancel := context.WithTimeout(context.Background(), ledgerTimeout)
+		defer cancel()
+		lerr := j.ContainerLedger.MarkFailed(lctx, j.ProviderKey, j.Container, err)
+		if lerr != nil {
+			logrus.WithError(lerr).WithFields(logrus.Fields{
+				"container": j.Container.String(),
+			}).Error("can't mark container as failed")
+		}
+		return nil, err
+	}
+
+	err = j.ContainerLedger.MarkDone(ctx, j.ProviderKey, j.Container, itemsCount)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't mark container as done")
+	}
+	return jobsOut, nil
+}
+
+// returns jobs for delayed properties and number of processed items
+func (j *JobContainerProcess) process(ctx context.Context) ([]app.IJob, int, error) {
 
 	if j.ProviderKey == "" {
-		return nil, errors.Errorf("provider key is not defined, job=%s", j.Name)
+		return nil, 0, errors.Errorf("provider key is not defined, job=%s", j.Name)
 	} else if j.ItemProvider == nil {
-		return nil, errors.Errorf("provider is not defined, job=%s", j.Name)
+		return nil, 0, errors.Errorf("provider is not defined, job=%s", j.Name)
 	} else if j.ItemRepository == nil {
-		return nil, errors.Errorf("repository is not defined, job=%s", j.Name)
+		return nil, 0, errors.Errorf("repository is not defined, job=%s", j.Name)
 	} else if j.Container == nil {
-		return nil, errors.Errorf("container is not defined, job=%s", j.Name)
+		return nil, 0, errors.Errorf("container is not defined, job=%s", j.Name)
 	}
 
-	items, err := j.ItemProvider.FetchContainerItems(j.Container)
+	items, err := j.ItemProvider.FetchContainerItems(ctx, j.Container)
 	if err != nil {
 		logrus.WithError(err).Error("can't fetch container items")
-		return nil, errors.Trace(err)
+		return nil, 0, errors.Trace(err)
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -61,9 +132,9 @@ func (j *JobContainerProcess) Execute() ([]app.IJob, error) {
 		props := item.GetRealtimeAutosetters()
 		for name, _ /*autosetter*/ := range props {
 			//we also could just call autosetter()
-			err := item.CallRealtimeAutosetter(name)
+			err := item.CallRealtimeAutosetter(ctx, name)
 			if err != nil {
-				return nil, errors.Annotatef(err, "can't autoset property name=%s", name)
+				return nil, 0, errors.Annotatef(err, "can't autoset property name=%s", name)
 			}
 			val, _ := reflections.GetField(item, name)
 			logrus.WithFields(logrus.Fields{
@@ -80,9 +151,9 @@ func (j *JobContainerProcess) Execute() ([]app.IJob, error) {
 		//fmt.Println(string(res))
 
 		//save item
-		err = j.ItemRepository.Save(item)
+		err = j.ItemRepository.Save(ctx, item)
 		if err != nil {
-			return nil, errors.Annotatef(err, "can't save item: %s", item)
+			return nil, 0, errors.Annotatef(err, "can't save item: %s", item)
 		}
 		logrus.WithFields(logrus.Fields{"item_id": item.GetId()}).Debug("item saved")
 
@@ -90,7 +161,7 @@ func (j *JobContainerProcess) Execute() ([]app.IJob, error) {
 		props = item.GetDelayedAutosetters()
 		for propName := range props {
 			//one property => one job
-			thejob := NewMessageJobPropertySet(j.ProviderKey, item.GetId(), propName)
+			thejob := NewMessageJobItemPropertySet(j.ProviderKey, item, propName)
 			jobsOut = append(jobsOut, thejob)
 			logrus.WithFields(logrus.Fields{
 				"job_name":         thejob.GetName(),
@@ -100,5 +171,74 @@ func (j *JobContainerProcess) Execute() ([]app.IJob, error) {
 
 	}
 
-	return jobsOut, nil
+	err = j.saveCalls(ctx, items)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	err = j.saveEvents(ctx)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+
+	return jobsOut, len(items), nil
+}
+
+/*
+calls are saved after items, so CallStats of items found in the same container are updated.
+Items could be called before their container was processed (by concurrent workers), so their stats are set too.
+*/
+func (j *JobContainerProcess) saveCalls(ctx context.Context, items []app.IItem) error {
+	if j.CallRepository == nil {
+		return nil
+	}
+	activityProvider, ok := j.ItemProvider.(app.IActivityProvider)
+	if !ok {
+		return errors.NotSupportedf("call tracking by provider=%s", j.ProviderKey)
+	}
+	calls, err := activityProvider.FetchContainerCalls(ctx, j.Container)
+	if err != nil {
+		return errors.Annotate(err, "can't fetch container calls")
+	}
+	err = j.CallRepository.SaveCalls(ctx, calls)
+	if err != nil {
+		return errors.Annotate(err, "can't save container calls")
+	}
+	if len(items) > 0 {
+		ids := make([]*app.ItemId, len(items))
+		for i, item := range items {
+			ids[i] = item.GetId()
+		}
+		err = j.CallRepository.UpdateCallStats(ctx, ids)
+		if err != nil {
+			return errors.Annotate(err, "can't update call stats of container items")
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"container":   j.Container.String(),
+		"calls_found": len(calls),
+	}).Info("FetchContainerCalls done")
+	return nil
+}
+
+func (j *JobContainerProcess) saveEvents(ctx context.Context) error {
+	if j.EventRepository == nil {
+		return nil
+	}
+	eventProvider, ok := j.ItemProvider.(app.IEventProvider)
+	if !ok {
+		return errors.NotSupportedf("event extraction by provider=%s", j.ProviderKey)
+	}
+	events, err := eventProvider.FetchContainerEvents(ctx, j.Container)
+	if err != nil {
+		return errors.Annotate(err, "can't fetch container events")
+	}
+	err = j.EventRepository.SaveEvents(ctx, events)
+	if err != nil {
+		return errors.Annotate(err, "can't save container events")
+	}
+	logrus.WithFields(logrus.Fields{
+		"container":    j.Container.String(),
+		"events_found": len(events),
+	}).Info("FetchContainerEvents done")
+	return nil
 }
@@ -1,7 +1,9 @@
 package job
 
 import (
+	"context"
 	"purrproof/smartcrawl/app"
+	"strings"
 
 	"github.com/juju/errors"
 
@@ -16,6 +18,9 @@ type JobPropertySet struct {
 	*app.Job
 	ItemId       *app.ItemId
 	PropertyName string
+	//empty for default kind of provider, see app.IMultiKindProvider
+	ItemKind      string             `json:",omitempty"`
+	SnapshotStore app.ISnapshotStore `json:"-"` //optional, it's passed to items storing snapshots of properties
 }
 
 /*
@@ -33,7 +38,32 @@ func NewMessageJobPropertySet(provKey string, itemId *app.ItemId, propName strin
 	}
 }
 
-func (j *JobPropertySet) Execute() ([]app.IJob, error) {
+// job message for item of any kind
+func NewMessageJobItemPropertySet(provKey string, item app.IItem, propName string) *JobPropertySet {
+	thejob := NewMessageJobPropertySet(provKey, item.GetId(), propName)
+	if kind := app.GetItemKind(item); kind != app.DefaultItemKind {
+		thejob.ItemKind = kind
+	}
+	return thejob
+}
+
+func (j *JobPropertySet) SetSnapshotStore(store app.ISnapshotStore) {
+	j.SnapshotStore = store
+}
+
+// one job per item property
+func (j *JobPropertySet) GetUniqueId() string {
+	if j.ItemId == nil || j.PropertyName == "" {
+		return ""
+	}
+	kind := ""
+	if j.ItemKind != "" {
+		kind = j.ItemKind + ":"
+	}
+	return j.Name + ":" + strings.ToLower(j.ProviderKey) + ":" + kind + j.ItemId.String() + ":" + j.PropertyName
+}
+
+func (j *JobPropertySet) Execute(ctx context.Context) ([]app.IJob, error) {
 
 	if j.ProviderKey == "" {
 		return nil, errors.Errorf("provider key is not defined, job=%s", j.Name)
@@ -47,17 +77,24 @@ func (j *JobPropertySet) Execute() ([]app.IJob, error) {
 		return nil, errors.Errorf("property name is not defined, job=%s", j.Name)
 	}
 
-	//create empty item with correct item id
-	emptyItem := j.ItemProvider.NewItem(j.ItemId.Id)
+	//create empty item of job kind with correct item id
+	emptyItem, err := app.NewProviderItem(j.ItemProvider, j.ItemKind, j.ItemId.Id)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't create item, item id: %s", j.ItemId.String())
+	}
 	//get item from repository
-	item, err := j.ItemRepository.Get(emptyItem)
+	item, err := j.ItemRepository.Get(ctx, emptyItem)
 	if err != nil {
 		return nil, errors.Annotatef(err, "can't get item from repository, item id: %s", j.ItemId.String())
 	} else if item == nil {
 		return nil, errors.Annotatef(err, "item not found in repository, item id: %s", j.ItemId.String())
 	}
 
-	err = item.CallAutosetter(j.PropertyName)
+	if snapshotItem, ok := item.(app.ISnapshotItem); ok && j.SnapshotStore != nil {
+		snapshotItem.SetSnapshotStore(j.SnapshotStore)
+	}
+
+	err = item.CallAutosetter(ctx, j.PropertyName)
 	if err != nil {
 		return nil, errors.Annotatef(err, "can't autoset property name=%s", j.PropertyName)
 	}
@@ -68,7 +105,7 @@ func (j *JobPropertySet) Execute() ([]app.IJob, error) {
 	}).Info("property set successfully")
 
 	//update item
-	err = j.ItemRepository.Update(item, []string{j.PropertyName})
+	err = j.ItemRepository.Update(ctx, item, []string{j.PropertyName})
 	if err != nil {
 		return nil, errors.Annotatef(err, "can't save item: %s", item)
 	}
@@ -0,0 +1,113 @@
+package job
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Add containers to queue one by one.
+If state is not nil, cursor is advanced after each successful enqueue.
+Stops when context is done, returns number of queued containers.
+*/
+func QueueContainers(ctx context.Context, provKey string, jobQueue app.IJobQueue, ledger app.IContainerLedger,
+	stateStore app.IAppStateStore, state *app.AppState, list []*app.ItemsContainer) (int, error) {
+	queued := 0
+	for _, container := range list {
+		if ctx.Err() != nil {
+			break
+		}
+		info, err := QueueContainer(ctx, provKey, jobQueue, ledger, container)
+		if err != nil {
+			return queued, errors.Trace(err)
+		} else if !info.Duplicate {
+			queued++
+		}
+
+		if state != nil {
+			state.LatestQueuedContainer = container
+			err = stateStore.Save(provKey, state)
+			if err != nil {
+				return queued, errors.Annotate(err, "can't save application state")
+			}
+		}
+	}
+	return queued, nil
+}
+
+/*
+Add container processing job to queue and record it in ledger.
+Container which is in queue already isn't added again, ledger isn't updated then (job may be processing).
+*/
+func QueueContainer(ctx context.Context, provKey string, jobQueue app.IJobQueue, ledger app.IContainerLedger,
+	container *app.ItemsContainer) (*app.JobInfo, error) {
+	jobIn := NewMessageJobContainerProcess(provKey, container)
+	queuedAt := time.Now()
+	info, err := jobQueue.Add(jobIn)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't add job to queue")
+	}
+	fields := logrus.Fields{
+		"id":        info.Id,
+		"queue":     info.Queue,
+		"container": container,
+	}
+	if info.Duplicate {
+		logrus.WithFields(fields).Info("job is in queue already, skipped")
+		return info, nil
+	}
+	logrus.WithFields(fields).Info("job queued")
+
+	err = ledger.MarkQueued(ctx, provKey, container, queuedAt)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't mark container as queued")
+	}
+	return info, nil
+}
+
+/*
+Single poll of follow mode: new containers after cursor are queued.
+Containers aren't queued while container queue holds follow.MaxQueueDepth jobs,
+otherwise not more than free place in queue, so workers aren't flooded.
+Returns number of queued containers.
+*/
+func FollowPoll(ctx context.Context, provKey string, provider app.IItemProvider, jobQueue app.IJobQueue,
+	ledger app.IContainerLedger, stateStore app.IAppStateStore, cursor string, follow *app.FollowConfig) (int, error) {
+
+	limit := follow.Limit
+	if follow.MaxQueueDepth > 0 {
+		depth, err := jobQueue.GetQueueDepth(JobTypeContainerProcess)
+		if err != nil {
+			return 0, errors.Annotate(err, "can't get queue depth")
+		} else if depth >= follow.MaxQueueDepth {
+			logrus.WithFields(logrus.Fields{
+				"depth":     depth,
+				"max_depth": follow.MaxQueueDepth,
+			}).Info("queue is full, skip poll")
+			return 0, nil
+		} else if free := uint(follow.MaxQueueDepth - depth); free < limit {
+			limit = free
+		}
+	}
+
+	state, err := stateStore.Get(provKey, cursor)
+	if err != nil {
+		return 0, errors.Annotate(err, "can't get app state")
+	}
+
+	list, err := provider.GetContainersList(ctx, limit, state.LatestQueuedContainer)
+	if err != nil {
+		return 0, errors.Annotate(err, "can't get containers list")
+	}
+
+	queued, err := QueueContainers(ctx, provKey, jobQueue, ledger, stateStore, state, list)
+	logrus.WithFields(logrus.Fields{
+		"queued": queued,
+		"found":  len(list),
+	}).Debug("follow poll done")
+	return queued, errors.Trace(err)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+/*
+Provider may expose several kinds of items, e.g. contract, transaction, account.
+Each kind has its own item type (with own autosetters) and its own collection of repository.
+Items of providers with single item type are of DefaultItemKind.
+*/
+
+// kind of items which don't report kind, they are stored in "item" collection as before kinds
+const DefaultItemKind = "contract"
+
+// item of kind other than DefaultItemKind
+type IKindedItem interface {
+	GetKind() string
+}
+
+// provider with several item kinds, NewItem creates items of the first one
+type IMultiKindProvider interface {
+	GetItemKinds() []string
+	NewItemOfKind(kind string, id string) (IItem, error)
+}
+
+func GetItemKind(item IItem) string {
+	if kinded, ok := item.(IKindedItem); ok && kinded.GetKind() != "" {
+		return kinded.GetKind()
+	}
+	return DefaultItemKind
+}
+
+func GetProviderItemKinds(provider IItemProvider) []string {
+	if multi, ok := provider.(IMultiKindProvider); ok {
+		return multi.GetItemKinds()
+	}
+	return []string{DefaultItemKind}
+}
+
+// kind is optional, NewItem of provider is used for empty kind
+func NewProviderItem(provider IItemProvider, kind string, id string) (IItem, error) {
+	if kind == "" {
+		return provider.NewItem(id), nil
+	}
+	if multi, ok := provider.(IMultiKindProvider); ok {
+		item, err := multi.NewItemOfKind(kind, id)
+		return item, errors.Trace(err)
+	} else if kind == DefaultItemKind {
+		return provider.NewItem(id), nil
+	}
+	return nil, errors.NotSupportedf("item kind=%s", kind)
+}
+
+/*
+KindProvider is provider whose NewItem creates items of one kind,
+so repository methods and commands taking provider work with items of that kind.
+*/
+type KindProvider struct {
+	IItemProvider
+	Kind string
+}
+
+// provider is returned as is for empty kind
+func NewKindProvider(provider IItemProvider, kind string) (IItemProvider, error) {
+	kind = strings.ToLower(kind)
+	if kind == "" {
+		return provider, nil
+	}
+	kinds := GetProviderItemKinds(provider)
+	for _, known := range kinds {
+		if known == kind {
+			return &KindProvider{IItemProvider: provider, Kind: kind}, nil
+		}
+	}
+	return nil, errors.NotSupportedf("item kind=%s, provider kinds: %s", kind, strings.Join(kinds, ", "))
+}
+
+func (p *KindProvider) NewItem(id string) IItem {
+	//kind is checked by NewKindProvider
+	item, _ := NewProviderItem(p.IItemProvider, p.Kind, id)
+	return item
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+/*
+Container ledger records processing status of each container (per provider),
+so lost containers (e.g. failed permanently after MaxRetry) can be found and queued again.
+*/
+
+const (
+	ContainerStatusQueued     = "queued"
+	ContainerStatusProcessing = "processing"
+	ContainerStatusDone       = "done"
+	ContainerStatusFailed     = "failed"
+	//there is no record in ledger
+	ContainerStatusMissing = "missing"
+)
+
+// asynq default timeout of task, it's used to find stale processing containers if TimeoutSec isn't set
+const DefaultJobTimeout = 30 * time.Minute
+
+type ContainerRecord struct {
+	ProviderKey string
+	//container.String()
+	Key       string
+	Container *ItemsContainer
+	//numeric container id, used for range queries, e.g. block number
+	Seq        uint
+	Status     string
+	Attempts   int
+	ItemsCount int
+	LastError  string
+	UpdatedAt  time.Time
+}
+
+type IContainerLedger interface {
+	//queuedAt is time before job is added to queue, failed record updated before it is queued again
+	MarkQueued(ctx context.Context, provKey string, container *ItemsContainer, queuedAt time.Time) error
+	//increments attempts counter
+	MarkProcessing(ctx context.Context, provKey string, container *ItemsContainer) error
+	MarkDone(ctx context.Context, provKey string, container *ItemsContainer, itemsCount int) error
+	MarkFailed(ctx context.Context, provKey string, container *ItemsContainer, reason error) error
+	//calls fn for each record with from <= seq <= to, ordered by seq
+	Walk(ctx context.Context, provKey string, from, to uint, fn func(record *ContainerRecord) error) error
+	Close() error
+}
+
+// range of containers (inclusive) with the same status
+type ContainerGap struct {
+	From   uint
+	To     uint
+	Status string
+}
+
+func (g *ContainerGap) Len() uint {
+	return g.To - g.From + 1
+}
+
+// containers of the gap, numeric ids only
+func (g *ContainerGap) Containers(limit uint) []*ItemsContainer {
+	result := make([]*ItemsContainer, 0)
+	for seq := g.From; seq <= g.To && uint(len(result)) < limit; seq++ {
+		result = append(result, NewItemsContainer([]string{strconv.Itoa(int(seq))}))
+	}
+	return result
+}
+
+/*
+GapFinder builds list of gaps from ledger records ordered by seq.
+Missing and failed containers are gaps, queued/processing containers are in flight and aren't reported,
+except processing containers not updated for longer than job timeout (worker was killed).
+*/
+type GapFinder struct {
+	from uint
+	to   uint
+	next uint
+	gaps []*ContainerGap
+	//processing records updated before are gaps, zero time means no such records
+	staleBefore time.Time
+}
+
+func NewGapFinder(from, to uint) *GapFinder {
+	return &GapFinder{
+		from: from,
+		to:   to,
+		next: from,
+		gaps: make([]*ContainerGap, 0),
+	}
+}
+
+// processing containers which aren't updated during timeout (including delay before retry) are reported as gaps
+func (f *GapFinder) SetProcessingTimeout(now time.Time, timeout time.Duration) {
+	f.staleBefore = now.Add(-timeout)
+}
+
+func (f *GapFinder) add(from, to uint, status string) {
+	last := len(f.gaps) - 1
+	if last >= 0 && f.gaps[last].Status == status && f.gaps[last].To+1 == from {
+		f.gaps[last].To = to
+		return
+	}
+	f.gaps = append(f.gaps, &ContainerGap{From: from, To: to, Status: status})
+}
+
+func (f *GapFinder) Add(record *ContainerRecord) {
+	if record.Seq < f.next || record.Seq > f.to {
+		return
+	}
+	if record.Seq > f.next {
+		f.add(f.next, record.Seq-1, ContainerStatusMissing)
+	}
+	if record.Status == ContainerStatusFailed {
+		f.add(record.Seq, record.Seq, ContainerStatusFailed)
+	} else if record.Status == ContainerStatusProcessing && record.UpdatedAt.Before(f.staleBefore) {
+		f.add(record.Seq, record.Seq, ContainerStatusProcessing)
+	}
+	f.next = record.Seq + 1
+}
+
+func (f *GapFinder) Gaps() []*ContainerGap {
+	result := f.gaps
+	if f.next <= f.to {
+		last := &ContainerGap{From: f.next, To: f.to, Status: ContainerStatusMissing}
+		result = append(result[:len(result):len(result)], last)
+	}
+	return result
+}
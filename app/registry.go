@@ -0,0 +1,102 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Provider packages register their constructors here under a provider type (usually in init()),
+e.g. "zilliqa", "evm".
+Each provider entry in config.json declares its type, so several entries may share one type,
+for example Zilliqa mainnet and testnet.
+*/
+
+type ItemProviderConstructor func(conf *ItemProviderConfig) (IItemProvider, error)
+
+var itemProviderTypes = make(map[string]ItemProviderConstructor, 0)
+
+// settings common for all provider types, they are decoded by app, not by provider package
+var commonProviderConfigKeys = map[string]bool{
+	"type":          true,
+	"follow":        true,
+	"ratelimit":     true,
+	"trackcalls":    true,
+	"extractevents": true,
+}
+
+func RegisterItemProviderType(provType string, constructor ItemProviderConstructor) {
+	provType = strings.ToLower(provType)
+	if _, found := itemProviderTypes[provType]; found {
+		panic("item provider type registered twice: " + provType)
+	}
+	itemProviderTypes[provType] = constructor
+}
+
+func GetItemProviderTypes() []string {
+	result := make([]string, 0, len(itemProviderTypes))
+	for provType := range itemProviderTypes {
+		result = append(result, provType)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func NewItemProvider(conf *ItemProviderConfig) (IItemProvider, error) {
+	provType := conf.GetType()
+	if provType == "" {
+		return nil, errors.New("provider type is not defined in config")
+	}
+	constructor, found := itemProviderTypes[provType]
+	if !found {
+		return nil, errors.Errorf("unknown provider type: %s, registered types: %s",
+			provType, strings.Join(GetItemProviderTypes(), ", "))
+	}
+	prov, err := constructor(conf)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't create provider of type=%s", provType)
+	}
+	return prov, nil
+}
+
+func (conf *ItemProviderConfig) GetType() string {
+	// Keys in the config map are in lowercase, as Viper reads them
+	provType, _ := (*conf)["type"].(string)
+	return strings.ToLower(provType)
+}
+
+/*
+Decode provider settings into provider specific config struct.
+Unknown keys are not fatal, they are only logged, because common settings (like Type) live in the same map.
+*/
+func (conf *ItemProviderConfig) Decode(out interface{}) error {
+	metadata := mapstructure.Metadata{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata:         &metadata,
+		WeaklyTypedInput: true,
+		Result:           out,
+	})
+	if err != nil {
+		return errors.Annotate(err, "can't create config decoder")
+	}
+
+	err = decoder.Decode(map[string]interface{}(*conf))
+	if err != nil {
+		return errors.Annotate(err, "can't decode provider config")
+	}
+
+	for _, key := range metadata.Unused {
+		if commonProviderConfigKeys[strings.Split(key, ".")[0]] {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"key":  key,
+			"type": conf.GetType(),
+		}).Warning("unknown provider config key")
+	}
+	return nil
+}
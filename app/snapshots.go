@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+/*
+Snapshots of item properties which change in time (e.g. contract state) are kept apart from items,
+so item document doesn't grow with history. Item keeps summary of the latest snapshot.
+*/
+
+type PropertySnapshot struct {
+	Item     *ItemId     `bson:",inline"`
+	Property string      `bson:"property"`
+	TakenAt  time.Time   `bson:"takenat"`
+	Value    interface{} `bson:"value"`
+}
+
+type ISnapshotStore interface {
+	//snapshot is unique by item, property and TakenAt, saving it again does nothing
+	SaveSnapshot(ctx context.Context, snapshot *PropertySnapshot) error
+	//deletes snapshots of item property except the latest ones
+	PruneSnapshots(ctx context.Context, id *ItemId, property string, keep int) error
+	Close() error
+}
+
+// item which stores snapshots of its properties, store is set by job:property:set
+type ISnapshotItem interface {
+	SetSnapshotStore(store ISnapshotStore)
+}
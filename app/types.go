@@ -1,6 +1,9 @@
 package app
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type IItemProvider interface {
 	NewItem(id string) IItem
@@ -9,7 +12,7 @@ type IItemProvider interface {
 		1) list of blocks
 		2) list of pages in software catalog
 	*/
-	GetContainersList(number uint, startAfter *ItemsContainer) ([]*ItemsContainer, error)
+	GetContainersList(ctx context.Context, number uint, startAfter *ItemsContainer) ([]*ItemsContainer, error)
 
 	/*
 		Returns items array from container.
@@ -18,45 +21,81 @@ type IItemProvider interface {
 		2) pattern matching html pieces from page in case of grabbing
 		3) applications/libraries from software category
 	*/
-	FetchContainerItems(container *ItemsContainer) ([]IItem, error)
+	FetchContainerItems(ctx context.Context, container *ItemsContainer) ([]IItem, error)
 	PrepareItemsArray(limit uint) []IItem
 	Close() error
 }
 
+// limits rate of provider requests, see Providers.<key>.RateLimit in config.json
+type IRateLimiter interface {
+	//blocks until request is allowed, returns error if ctx is done earlier
+	Wait(ctx context.Context) error
+	Close() error
+}
+
+// provider which applies rate limit to its requests, limiter is injected by factory
+type IRateLimitedProvider interface {
+	SetRateLimiter(limiter IRateLimiter)
+}
+
 type IItem interface {
 	HasAutosetField(name string) bool
 	RegisterAutosetters() error
-	RegisterRealtimeAutosetter(name string, p func() error)
-	RegisterDelayedAutosetter(name string, p func() error)
-	GetRealtimeAutosetters() map[string]func() error
-	GetDelayedAutosetters() map[string]func() error
-	CallRealtimeAutosetter(name string) error
-	CallAllRealtimeAutosetters()
-	CallAutosetter(name string) error
+	RegisterRealtimeAutosetter(name string, p func(ctx context.Context) error)
+	RegisterDelayedAutosetter(name string, p func(ctx context.Context) error)
+	GetRealtimeAutosetters() map[string]func(ctx context.Context) error
+	GetDelayedAutosetters() map[string]func(ctx context.Context) error
+	CallRealtimeAutosetter(ctx context.Context, name string) error
+	CallAllRealtimeAutosetters(ctx context.Context)
+	CallAutosetter(ctx context.Context, name string) error
 	SetBaseField(fieldName string, fieldValue interface{}) error
 	GetId() *ItemId
 	GetProviderFilter() map[string]interface{}
 }
 
+// item whose id has several encodings, e.g. address in hex or bech32, id is converted to the canonical one
+type INormalizableItem interface {
+	NormalizeId() error
+}
+
 type IItemRepository interface {
-	Get(item IItem) (IItem, error)
-	GetAllWithoutProperty(provider IItemProvider, propName string, limit uint) ([]IItem, error)
-	Save(item IItem) error
-	Update(item IItem, fieldNames []string) error
+	Get(ctx context.Context, item IItem) (IItem, error)
+	GetAllWithoutProperty(ctx context.Context, provider IItemProvider, propName string, limit uint) ([]IItem, error)
+	//items whose property is snapshot taken before time, see SnapshotTimeField
+	GetAllWithStaleProperty(ctx context.Context, provider IItemProvider, propName string, before time.Time, limit uint) ([]IItem, error)
+	//groups of items with the same value of property, the largest first, see ItemGroup
+	GetGroups(ctx context.Context, provider IItemProvider, groupBy string, fields []string, minCount uint, limit uint) ([]*ItemGroup, error)
+	Save(ctx context.Context, item IItem) error
+	Update(ctx context.Context, item IItem, fieldNames []string) error
 	Close() error
 }
 
+/*
+Items with the same value of property, e.g. clones of contract with the same CodeHash.
+Items are ordered by the first of requested fields, only id and requested fields are decoded.
+*/
+type ItemGroup struct {
+	Value interface{}
+	Count int
+	Items []IItem
+}
+
 type IJob interface {
 	GetName() string
 	GetDefaultQueueName() string
 	GetProviderKey() string
 	SetItemRepository(repository IItemRepository)
 	SetItemProvider(provider IItemProvider)
-	Execute() ([]IJob, error)
+	Execute(ctx context.Context) ([]IJob, error)
+	//deterministic id derived from job content, queue doesn't add a job if the job with the same id is waiting already
+	//empty id disables deduplication
+	GetUniqueId() string
 }
 
 type IJobQueue interface {
 	Add(job IJob, params ...string) (*JobInfo, error)
+	//number of jobs in queue which aren't finished yet (pending, active, scheduled, waiting for retry)
+	GetQueueDepth(queue string) (int, error)
 	Close() error
 	Process(queue string, workersNum uint) error
 }
@@ -64,15 +103,36 @@ type IJobQueue interface {
 type JobInfo struct {
 	Id    string
 	Queue string
+	//job with the same unique id is in queue already, the job isn't added
+	Duplicate bool
 }
 
+// field of property document with time the property value was taken at, for properties refreshed periodically (e.g. contract state)
+const SnapshotTimeField = "takenat"
+
+// cursor used by queue-container-process when cursor name isn't specified
+const DefaultCursorName = "queue"
+
+/*
+Crawl cursor, one per provider key and cursor name.
+Provider key is stored in lowercase, as Viper reads config keys.
+*/
 type AppState struct {
+	ProviderKey           string
+	Cursor                string
 	LatestQueuedContainer *ItemsContainer
 	UpdatedAt             time.Time
 }
 
 type IAppStateStore interface {
-	Get() (*AppState, error)
-	Save(info *AppState) error
+	//cursor name is optional, DefaultCursorName by default
+	Get(provKey string, cursor ...string) (*AppState, error)
+	//cursor name is taken from state, DefaultCursorName if empty
+	Save(provKey string, state *AppState) error
+	//all cursors of provider
+	GetAll(provKey string) ([]*AppState, error)
+	Reset(provKey string, cursor ...string) error
+	//assign legacy state (saved before per-provider cursors) to provider key
+	Migrate(provKey string) (bool, error)
 	Close() error
 }
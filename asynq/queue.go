@@ -3,7 +3,11 @@ package asynq
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"purrproof/smartcrawl/app"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/juju/errors"
@@ -15,12 +19,17 @@ var _ app.IJobQueue = (*JobQueue)(nil)
 type JobQueue struct {
 	WorkersNum uint
 	client     *asynq.Client
+	inspector  *asynq.Inspector
 	config     *app.QueueConfig
-	jobHandler func(payload []byte) ([]app.IJob, error)
+	jobHandler func(ctx context.Context, payload []byte) ([]app.IJob, error)
+
+	//Add is called by many workers at once (follow-up jobs), so client and inspector are created once
+	clientOnce    sync.Once
+	inspectorOnce sync.Once
 }
 
 //error is impossible here, but it could be possible in other library, so lets keep error in return
-func NewJobQueue(conf *app.QueueConfig, jobHandler func(payload []byte) ([]app.IJob, error)) (*JobQueue, error) {
+func NewJobQueue(conf *app.QueueConfig, jobHandler func(ctx context.Context, payload []byte) ([]app.IJob, error)) (*JobQueue, error) {
 	jobQueue := &JobQueue{
 		WorkersNum: uint(1),
 		config:     conf,
@@ -30,21 +39,19 @@ func NewJobQueue(conf *app.QueueConfig, jobHandler func(payload []byte) ([]app.I
 	return jobQueue, nil
 }
 
-func (q *JobQueue) setClient() {
-	client := asynq.NewClient(asynq.RedisClientOpt{
-		Addr:     q.config.Addr,
-		Password: q.config.Password,
+func (q *JobQueue) getClient() *asynq.Client {
+	q.clientOnce.Do(func() {
+		q.client = asynq.NewClient(asynq.RedisClientOpt{
+			Addr:     q.config.Addr,
+			Password: q.config.Password,
+		})
 	})
-	q.client = client
+	return q.client
 }
 
 func (q *JobQueue) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
 	//queueName = params[0] if set
 
-	if q.client == nil {
-		q.setClient()
-	}
-
 	payload, err := json.Marshal(job)
 	if err != nil {
 		return nil, errors.Annotate(err, "can't unmarshal job")
@@ -52,12 +59,7 @@ func (q *JobQueue) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
 
 	//https://github.com/hibiken/asynq#quickstart
 	//https://github.com/hibiken/asynq/wiki/Queue-Priority
-	//task options can be passed to NewTask, which can be overridden at enqueue time.
-	//asynq.Queue("critical") config
-	//asynq.MaxRetry(5) config.Job.MaxRetry
-	//asynq.Timeout(20*time.Minute) config.Job.TimeoutSec
-	//other options
-	//asynq has no priority for single job, only for queue
+	//asynq has no priority for single job, only for queue, see Process
 
 	var queueName string
 	if len(params) == 0 {
@@ -66,10 +68,18 @@ func (q *JobQueue) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
 	} else {
 		queueName = params[0]
 	}
-	task := asynq.NewTask(job.GetName(), payload, asynq.Queue(queueName))
+	opts := q.taskOptions(queueName)
+	taskId := job.GetUniqueId()
+	if taskId != "" {
+		opts = append(opts, asynq.TaskID(taskId))
+	}
+	//task type is queue name, so retry delay function finds job config of queue (see retryDelayFunc)
+	task := asynq.NewTask(queueName, payload, opts...)
 
-	info, err := q.client.Enqueue(task)
-	if err != nil {
+	info, err := q.getClient().Enqueue(task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return q.enqueueConflicted(task, queueName, taskId)
+	} else if err != nil {
 		return nil, errors.Annotate(err, "can't enqueue job")
 	}
 
@@ -80,6 +90,93 @@ func (q *JobQueue) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
 	return jobInfo, nil
 }
 
+/*
+Task with the same id exists in queue.
+Task ids stay reserved until task is deleted, so failed (archived) and completed tasks are replaced,
+otherwise backfill couldn't queue failed containers again.
+Waiting and running tasks are reported as duplicates.
+*/
+func (q *JobQueue) enqueueConflicted(task *asynq.Task, queueName, taskId string) (*app.JobInfo, error) {
+	inspector := q.getInspector()
+	existing, err := inspector.GetTaskInfo(queueName, taskId)
+	if err == nil && existing.State != asynq.TaskStateArchived && existing.State != asynq.TaskStateCompleted {
+		logrus.WithFields(logrus.Fields{
+			"id":    existing.ID,
+			"queue": existing.Queue,
+			"state": existing.State.String(),
+		}).Debug("job is in queue already")
+		return &app.JobInfo{
+			Id:        existing.ID,
+			Queue:     existing.Queue,
+			Duplicate: true,
+		}, nil
+	} else if err == nil {
+		err = inspector.DeleteTask(queueName, taskId)
+	}
+	//task could be processed and deleted meanwhile
+	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		return nil, errors.Annotatef(err, "can't replace job, id=%s", taskId)
+	}
+
+	info, err := q.getClient().Enqueue(task)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't enqueue job")
+	}
+	return &app.JobInfo{
+		Id:    info.ID,
+		Queue: info.Queue,
+	}, nil
+}
+
+// options from job config of queue, zero values are left to asynq defaults
+func (q *JobQueue) taskOptions(queueName string) []asynq.Option {
+	opts := []asynq.Option{asynq.Queue(queueName)}
+	jconf := q.config.GetJobConfig(queueName)
+	if maxRetry, defined := jconf.GetMaxRetry(); defined {
+		opts = append(opts, asynq.MaxRetry(maxRetry))
+	}
+	if jconf.TimeoutSec > 0 {
+		opts = append(opts, asynq.Timeout(time.Duration(jconf.TimeoutSec)*time.Second))
+	}
+	return opts
+}
+
+func (q *JobQueue) getInspector() *asynq.Inspector {
+	q.inspectorOnce.Do(func() {
+		q.inspector = asynq.NewInspector(asynq.RedisClientOpt{
+			Addr:     q.config.Addr,
+			Password: q.config.Password,
+		})
+	})
+	return q.inspector
+}
+
+func (q *JobQueue) GetQueueDepth(queueName string) (int, error) {
+	inspector := q.getInspector()
+
+	//queue info of not existing queue is an error, but for us it's just empty queue
+	queues, err := inspector.Queues()
+	if err != nil {
+		return 0, errors.Annotate(err, "can't get queues list")
+	}
+	found := false
+	for _, name := range queues {
+		if name == queueName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, nil
+	}
+
+	info, err := inspector.GetQueueInfo(queueName)
+	if err != nil {
+		return 0, errors.Annotatef(err, "can't get queue info, queue=%s", queueName)
+	}
+	return info.Pending + info.Active + info.Scheduled + info.Retry, nil
+}
+
 func (q *JobQueue) Close() error {
 	if q.client != nil {
 		err := q.client.Close()
@@ -87,15 +184,42 @@ func (q *JobQueue) Close() error {
 			return errors.Annotate(err, "can't close job queue")
 		}
 	}
+	if q.inspector != nil {
+		err := q.inspector.Close()
+		if err != nil {
+			return errors.Annotate(err, "can't close queue inspector")
+		}
+	}
 	logrus.Info("queue closed")
 	return nil
 }
 
+/*
+qname may contain several comma separated queue names,
+in that case workers take jobs from them according to their priorities.
+*/
 func (q *JobQueue) Process(qname string, workersNum uint) error {
 	if workersNum == 0 {
 		workersNum = 1
 	}
 	q.WorkersNum = workersNum
+
+	queues := make(map[string]int, 0)
+	for _, name := range strings.Split(qname, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		priority := q.config.GetJobConfig(name).Priority
+		if priority <= 0 {
+			priority = 1
+		}
+		queues[name] = priority
+	}
+	if len(queues) == 0 {
+		return errors.New("queue name is not defined")
+	}
+
 	srv := asynq.NewServer(
 		asynq.RedisClientOpt{
 			Addr:     q.config.Addr,
@@ -104,13 +228,9 @@ func (q *JobQueue) Process(qname string, workersNum uint) error {
 		asynq.Config{
 			// Specify how many concurrent workers to use
 			Concurrency: int(q.WorkersNum),
-			// Optionally specify multiple queues with different
-			Queues: map[string]int{
-				/*"critical": 6,
-				"default":  3,
-				"low":      1,*/
-				qname: 10,
-			},
+			// Queues with their priorities (weights)
+			Queues:         queues,
+			RetryDelayFunc: q.retryDelayFunc(queues),
 			// See the godoc for other configuration options
 		},
 	)
@@ -123,7 +243,8 @@ func (q *JobQueue) Process(qname string, workersNum uint) error {
 	// Patterns are also accepted, but they work based on hasprefix, comparing the start of the string.
 	// typ := "*" the asterisk won't work.
 	// All tasks starting with job: will be processed, i.e., basically all tasks.
-	// But since we have only one type of task in a queue, this is not a problem.	mux.HandleFunc("job:", q.handleTask)
+	// But since we have only one type of task in a queue, this is not a problem.
+	mux.HandleFunc("job:", q.handleTask)
 
 	//mux.Handle(tasks.TypeImageResize, tasks.NewImageProcessor())
 	// ...register other handlers...
@@ -143,11 +264,35 @@ func (q *JobQueue) Process(qname string, workersNum uint) error {
 	return nil
 }
 
+/*
+asynq doesn't pass queue name into retry delay function.
+By convention one queue holds one type of task: task type is queue name, job name is in payload.
+Tasks queued before had job name as type, their queue is known if server listens single queue.
+*/
+func (q *JobQueue) retryDelayFunc(queues map[string]int) asynq.RetryDelayFunc {
+	return func(n int, e error, t *asynq.Task) time.Duration {
+		name := t.Type()
+		if len(queues) == 1 {
+			for qname := range queues {
+				name = qname
+			}
+		}
+		delay, defined := q.config.GetJobConfig(name).RetryDelay(n)
+		if !defined {
+			return asynq.DefaultRetryDelayFunc(n, e, t)
+		}
+		return delay
+	}
+}
+
 func (q *JobQueue) handleTask(ctx context.Context, t *asynq.Task) error {
 	//options: https://github.com/hibiken/asynq/blob/9116c096ecf3e8493f9997d2b906fa847b0d1a2c/client.go#L67
 	//fmt.Println(t.Type())
 	//fmt.Println(t.Payload())
-	newJobs, err := q.jobHandler(t.Payload())
+	//ctx is done on task timeout/deadline and on server shutdown
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	newJobs, err := q.jobHandler(app.WithJobAttempt(ctx, retried, maxRetry), t.Payload())
 	for _, job := range newJobs {
 		//add job to queue
 		info, err := q.Add(job)
@@ -159,5 +304,9 @@ func (q *JobQueue) handleTask(ctx context.Context, t *asynq.Task) error {
 			"queue": info.Queue,
 		}).Info("job queued")
 	}
+	if app.IsPermanentError(err) {
+		//retry doesn't help, task is archived at once
+		return fmt.Errorf("%s: %w", err.Error(), asynq.SkipRetry)
+	}
 	return err
 }
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"purrproof/smartcrawl/app"
 	"purrproof/smartcrawl/app/job"
@@ -25,6 +31,15 @@ const (
 	flagContainerReq string = "container"
 	flagProperty     string = "property"
 	flagItem         string = "item"
+	flagCursor       string = "cursor"
+	flagFrom         string = "from"
+	flagTo           string = "to"
+	flagInterval     string = "interval"
+	flagMaxDepth     string = "max-depth"
+	flagWorkers      string = "workers"
+	flagOlderThan    string = "older-than"
+	flagMinCount     string = "min-count"
+	flagKind         string = "kind"
 )
 
 type CliFlags struct {
@@ -37,6 +52,15 @@ type CliFlags struct {
 	ContainerReq cli.Flag
 	Property     cli.Flag
 	Item         cli.Flag
+	Cursor       cli.Flag
+	From         cli.Flag
+	To           cli.Flag
+	Interval     cli.Flag
+	MaxDepth     cli.Flag
+	Workers      cli.Flag
+	OlderThan    cli.Flag
+	MinCount     cli.Flag
+	Kind         cli.Flag
 }
 
 var cliFlags = CliFlags{
@@ -51,9 +75,16 @@ var cliFlags = CliFlags{
 	Provider: &cli.StringFlag{
 		Name:       flagProvider,
 		Persistent: true,
-		Usage:      "provider",
+		Usage:      "provider key from config.json",
 		Required:   true,
 	},
+	Kind: &cli.StringFlag{
+		Name:       flagKind,
+		Persistent: true,
+		Value:      "",
+		Usage:      "item kind of provider, e.g. contract or transaction; default kind of provider if empty",
+		Required:   false,
+	},
 	LogLevel: &cli.StringFlag{
 		Name:       flagLogLevel,
 		Persistent: true,
@@ -71,7 +102,7 @@ var cliFlags = CliFlags{
 	Queue: &cli.StringFlag{
 		Name:     flagQueue,
 		Value:    "",
-		Usage:    "queue name",
+		Usage:    "queue name, comma separated names to listen several queues",
 		Required: true,
 	},
 	Container: &cli.StringSliceFlag{
@@ -95,9 +126,61 @@ var cliFlags = CliFlags{
 	Item: &cli.StringFlag{
 		Name:     flagItem,
 		Value:    "",
-		Usage:    "item id",
+		Usage:    "item id, e.g. contract address in any form (hex, checksummed hex, bech32)",
+		Required: true,
+	},
+	Cursor: &cli.StringFlag{
+		Name:     flagCursor,
+		Value:    app.DefaultCursorName,
+		Usage:    "crawl cursor name",
+		Required: false,
+	},
+	From: &cli.UintFlag{
+		Name:     flagFrom,
+		Value:    0,
+		Usage:    "first container of range (numeric container ids only)",
+		Required: false,
+	},
+	To: &cli.UintFlag{
+		Name:     flagTo,
+		Value:    0,
+		Usage:    "last container of range, latest queued container by default",
+		Required: false,
+	},
+	Interval: &cli.DurationFlag{
+		Name:     flagInterval,
+		Usage:    "pause between polls, overrides Follow.IntervalSec from config",
+		Required: false,
+	},
+	MaxDepth: &cli.IntFlag{
+		Name:     flagMaxDepth,
+		Usage:    "max number of unfinished jobs in queue, overrides Follow.MaxQueueDepth from config",
+		Required: false,
+	},
+	Workers: &cli.UintFlag{
+		Name:     flagWorkers,
+		Value:    1,
+		Usage:    "number of workers",
+		Required: false,
+	},
+	OlderThan: &cli.DurationFlag{
+		Name:     flagOlderThan,
+		Usage:    "age of property snapshot, e.g. 24h",
 		Required: true,
 	},
+	MinCount: &cli.UintFlag{
+		Name:     flagMinCount,
+		Value:    2,
+		Usage:    "min number of items in group",
+		Required: false,
+	},
+}
+
+// commands which process their jobs in memory queue, Redis isn't needed for them
+var localJobCommands = map[string]bool{
+	"crawl":                  true,
+	"exec-container-process": true,
+	"exec-property-set":      true,
 }
 
 var appConfig *app.AppConfig
@@ -111,6 +194,7 @@ func main() {
 		Flags: []cli.Flag{
 			cliFlags.Env,
 			cliFlags.Provider,
+			cliFlags.Kind,
 			cliFlags.LogLevel,
 		},
 		Commands: []*cli.Command{
@@ -118,7 +202,16 @@ func main() {
 			CmdExecPropertySet(),
 			CmdQueueContainerProcess(),
 			CmdQueuePropertyAdd(),
+			CmdQueuePropertyRefresh(),
 			CmdWorker(),
+			CmdStateShow(),
+			CmdStateReset(),
+			CmdStateMigrate(),
+			CmdGaps(),
+			CmdClones(),
+			CmdBackfill(),
+			CmdFollow(),
+			CmdCrawl(),
 		},
 		Before: func(c *cli.Context) error {
 			//load environment variables from file
@@ -137,6 +230,7 @@ func main() {
 
 			//init factory
 			factory = factory_pkg.NewFactory(appConfig)
+			factory.LocalJobs = localJobCommands[c.Args().First()]
 
 			//init provider
 			providerKey = c.String(flagProvider)
@@ -144,6 +238,11 @@ func main() {
 			if err != nil {
 				return errors.Trace(err)
 			}
+			//items of commands (property jobs, clones) are of this kind
+			provider, err = app.NewKindProvider(provider, c.String(flagKind))
+			if err != nil {
+				return errors.Annotatef(err, "invalid item kind for provider=%s", providerKey)
+			}
 
 			//init logger
 			//not found a way to modify LogLevel flag default, so use if
@@ -166,7 +265,10 @@ func main() {
 			return nil
 		},
 	}
-	if err := cliapp.Run(os.Args); err != nil {
+	//SIGINT/SIGTERM cancel context of running command
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := cliapp.RunContext(ctx, os.Args); err != nil {
 		logrus.Fatal(errors.ErrorStack(err))
 	}
 	if factory != nil {
@@ -187,37 +289,17 @@ func CmdExecContainerProcess() *cli.Command {
 			containerId := c.StringSlice(flagContainer)
 			container := app.NewItemsContainer(containerId)
 
-			//repository
-			repository, err := factory.GetItemRepository()
+			//jobs are processed in this process, property set jobs are added by container job
+			jobQueue, err := factory.NewMemoryJobQueue()
 			if err != nil {
 				return errors.Trace(err)
 			}
-
-			//create job message
-			thejob := job.NewMessageJobContainerProcess(providerKey, container)
-			thejob.SetItemProvider(provider)
-			thejob.SetItemRepository(repository)
-
-			newJobs, err := thejob.Execute()
+			_, err = jobQueue.Add(job.NewMessageJobContainerProcess(providerKey, container))
 			if err != nil {
-				return errors.Annotate(err, "can't execute job")
+				return errors.Annotate(err, "can't add job to queue")
 			}
 
-			//execute returned property set jobs
-			for _, jobPropertySet := range newJobs {
-				jobPropertySet.SetItemProvider(provider)
-				jobPropertySet.SetItemRepository(repository)
-				//this job don't return any other jobs
-				_, err := jobPropertySet.Execute()
-				if err != nil {
-					logrus.WithError(err).WithFields(logrus.Fields{
-						"job": jobPropertySet,
-					}).Warning("can't execute job")
-					return errors.Annotate(err, "can't execute job")
-				}
-			}
-
-			return nil
+			return processJobs(c.Context, jobQueue, 1, job.JobTypeContainerProcess, job.JobTypePropertySet+":*")
 		},
 	}
 }
@@ -233,8 +315,12 @@ func CmdExecPropertySet() *cli.Command {
 		},
 		Action: func(c *cli.Context) error {
 
+			//any form of id is accepted, e.g. contract address in hex or bech32
 			itemId := c.String(flagItem)
 			item := provider.NewItem(itemId)
+			if err := app.NormalizeItemId(item); err != nil {
+				return errors.Annotatef(err, "invalid item id=%s", itemId)
+			}
 
 			//get property name
 			propName := c.String(flagProperty)
@@ -242,24 +328,17 @@ func CmdExecPropertySet() *cli.Command {
 				return errors.Errorf("not found property name=%s for provider=%s", propName, providerKey)
 			}
 
-			//repository
-			repository, err := factory.GetItemRepository()
+			//create job
+			jobQueue, err := factory.NewMemoryJobQueue()
 			if err != nil {
 				return errors.Trace(err)
 			}
-
-			//create job
-			thejob := job.NewMessageJobPropertySet(providerKey, item.GetId(), propName)
-			thejob.SetItemProvider(provider)
-			thejob.SetItemRepository(repository)
-
-			//this job don't return any other jobs
-			_, err = thejob.Execute()
+			_, err = jobQueue.Add(job.NewMessageJobItemPropertySet(providerKey, item, propName))
 			if err != nil {
-				return errors.Annotate(err, "can't execute job")
+				return errors.Annotate(err, "can't add job to queue")
 			}
 
-			return nil
+			return processJobs(c.Context, jobQueue, 1, job.JobTypePropertySet+":*")
 		},
 	}
 }
@@ -272,6 +351,7 @@ func CmdQueueContainerProcess() *cli.Command {
 		Flags: []cli.Flag{
 			cliFlags.Limit,
 			cliFlags.Container,
+			cliFlags.Cursor,
 		},
 		Action: func(c *cli.Context) error {
 
@@ -282,7 +362,7 @@ func CmdQueueContainerProcess() *cli.Command {
 			}
 
 			//get app state
-			state, err := stateStore.Get()
+			state, err := stateStore.Get(providerKey, c.String(flagCursor))
 			if err != nil {
 				return errors.Annotate(err, "can't get app state store")
 			}
@@ -304,7 +384,7 @@ func CmdQueueContainerProcess() *cli.Command {
 			containersNum := c.Uint(flagLimit)
 
 			//get containers list
-			list, err := provider.GetContainersList(containersNum, startContainer)
+			list, err := provider.GetContainersList(c.Context, containersNum, startContainer)
 			if err != nil {
 				return errors.Annotate(err, "can't get containers list")
 			}
@@ -315,30 +395,19 @@ func CmdQueueContainerProcess() *cli.Command {
 				return errors.Trace(err)
 			}
 
-			//add jobs to queue in cycle
-			for _, container := range list {
-				jobIn := job.NewMessageJobContainerProcess(providerKey, container)
-				//add job to queue
-				info, err := jobQueue.Add(jobIn)
-				if err != nil {
-					return errors.Annotate(err, "can't add job to queue")
-				}
-				logrus.WithFields(logrus.Fields{
-					"id":        info.Id,
-					"queue":     info.Queue,
-					"container": container,
-				}).Info("job queued")
-
-				if saveState {
-					state.LatestQueuedContainer = container
-					stateStore.Save(state)
-				}
-				if err != nil {
-					return errors.Annotate(err, "can't save application state")
-				}
+			//init ledger
+			ledger, err := factory.GetContainerLedger()
+			if err != nil {
+				return errors.Trace(err)
 			}
 
-			return nil
+			//add jobs to queue in cycle
+			var stateToSave *app.AppState
+			if saveState {
+				stateToSave = state
+			}
+			_, err = job.QueueContainers(c.Context, providerKey, jobQueue, ledger, stateStore, stateToSave, list)
+			return errors.Trace(err)
 		},
 	}
 }
@@ -374,7 +443,7 @@ func CmdQueuePropertyAdd() *cli.Command {
 			}
 
 			//get items without property
-			items, err := repository.GetAllWithoutProperty(provider, propName, limit)
+			items, err := repository.GetAllWithoutProperty(c.Context, provider, propName, limit)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -389,17 +458,24 @@ func CmdQueuePropertyAdd() *cli.Command {
 			i := 0
 			for _, item := range items {
 				//create job message
-				jobmsg := job.NewMessageJobPropertySet(providerKey, item.GetId(), propName)
+				jobmsg := job.NewMessageJobItemPropertySet(providerKey, item, propName)
 
 				//add job to queue
 				info, err := jobQueue.Add(jobmsg)
 				if err != nil {
 					return errors.Annotate(err, "can't add job to queue")
+				} else if info.Duplicate {
+					//job is waiting already, item is marked as processed anyway
+					logrus.WithFields(logrus.Fields{
+						"item_id":       item.GetId(),
+						"property_name": propName,
+						"job_id":        info.Id,
+					}).Debug("job is in queue already")
 				}
 
 				//mark item field as processed
 				reflections.SetField(item, propName, "")
-				err = repository.Update(item, []string{propName})
+				err = repository.Update(c.Context, item, []string{propName})
 				if err != nil {
 					return errors.Annotatef(err, "can't save item: %s", item)
 				}
@@ -422,6 +498,77 @@ func CmdQueuePropertyAdd() *cli.Command {
 	}
 }
 
+func CmdQueuePropertyRefresh() *cli.Command {
+
+	return &cli.Command{
+		Name:  "queue-property-refresh",
+		Usage: "queue job:property:set jobs for items whose property snapshot (e.g. contract State) is older than --older-than",
+		Flags: []cli.Flag{
+			cliFlags.Property,
+			cliFlags.Limit,
+			cliFlags.OlderThan,
+		},
+		Action: func(c *cli.Context) error {
+
+			limit := c.Uint(flagLimit)
+			if limit == 0 {
+				return errors.New("Limit must be greater than 0")
+			}
+			olderThan := c.Duration(flagOlderThan)
+			if olderThan <= 0 {
+				return errors.New("older-than must be greater than 0")
+			}
+
+			propName := c.String(flagProperty)
+			testItem := provider.NewItem("test")
+			if !testItem.HasAutosetField(propName) {
+				return errors.Errorf("not found property name=%s for provider=%s", propName, providerKey)
+			}
+
+			repository, err := factory.GetItemRepository()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			items, err := repository.GetAllWithStaleProperty(c.Context, provider, propName, time.Now().Add(-olderThan), limit)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			logrus.WithFields(logrus.Fields{"number": len(items)}).Info("got items")
+
+			jobQueue, err := factory.GetJobQueue()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			//item isn't marked, job with the same item and property isn't queued twice (see IJob.GetUniqueId)
+			queued := 0
+			for _, item := range items {
+				jobmsg := job.NewMessageJobItemPropertySet(providerKey, item, propName)
+				info, err := jobQueue.Add(jobmsg)
+				if err != nil {
+					return errors.Annotate(err, "can't add job to queue")
+				} else if info.Duplicate {
+					logrus.WithFields(logrus.Fields{
+						"item_id":       item.GetId(),
+						"property_name": propName,
+						"job_id":        info.Id,
+					}).Debug("job is in queue already")
+					continue
+				}
+				queued++
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"number":  len(items),
+				"queued":  queued,
+				"skipped": len(items) - queued,
+			}).Info("items queued for refresh")
+
+			return nil
+		},
+	}
+}
+
 func CmdWorker() *cli.Command {
 
 	return &cli.Command{
@@ -444,10 +591,472 @@ func CmdWorker() *cli.Command {
 			//get queue name flag
 			qname := c.String(flagQueue)
 
-			jobQueue.Process(qname, workersNum)
+			err = jobQueue.Process(qname, workersNum)
+			if err != nil {
+				return errors.Trace(err)
+			}
 
 			return nil
 
 		},
 	}
 }
+
+func CmdStateShow() *cli.Command {
+
+	return &cli.Command{
+		Name:  "state-show",
+		Usage: "show crawl cursors of provider",
+		Action: func(c *cli.Context) error {
+
+			stateStore, err := factory.GetAppStateStore()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			states, err := stateStore.GetAll(providerKey)
+			if err != nil {
+				return errors.Annotate(err, "can't get app state")
+			}
+
+			for _, state := range states {
+				latest := ""
+				if state.LatestQueuedContainer != nil {
+					latest = state.LatestQueuedContainer.String()
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\n", state.ProviderKey, state.Cursor, latest, state.UpdatedAt.Format(time.RFC3339))
+			}
+			if len(states) == 0 {
+				logrus.WithFields(logrus.Fields{
+					"provider": providerKey,
+				}).Info("no cursors found")
+			}
+
+			return nil
+		},
+	}
+}
+
+func CmdStateReset() *cli.Command {
+
+	return &cli.Command{
+		Name:  "state-reset",
+		Usage: "reset crawl cursor of provider, next queue-container-process starts from the beginning or after specified container",
+		Flags: []cli.Flag{
+			cliFlags.Cursor,
+			cliFlags.Container,
+		},
+		Action: func(c *cli.Context) error {
+
+			stateStore, err := factory.GetAppStateStore()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			cursor := c.String(flagCursor)
+			containerId := c.StringSlice(flagContainer)
+			if len(containerId) == 0 {
+				err = stateStore.Reset(providerKey, cursor)
+			} else {
+				state := &app.AppState{
+					Cursor:                cursor,
+					LatestQueuedContainer: app.NewItemsContainer(containerId),
+				}
+				err = stateStore.Save(providerKey, state)
+			}
+			if err != nil {
+				return errors.Annotate(err, "can't reset app state")
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"provider":  providerKey,
+				"cursor":    cursor,
+				"container": containerId,
+			}).Info("cursor reset")
+
+			return nil
+		},
+	}
+}
+
+func CmdStateMigrate() *cli.Command {
+
+	return &cli.Command{
+		Name:  "state-migrate",
+		Usage: "assign state saved before per-provider cursors to provider (default cursor)",
+		Action: func(c *cli.Context) error {
+
+			stateStore, err := factory.GetAppStateStore()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			migrated, err := stateStore.Migrate(providerKey)
+			if err != nil {
+				return errors.Annotate(err, "can't migrate app state")
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"provider": providerKey,
+				"migrated": migrated,
+			}).Info("state migration done")
+
+			return nil
+		},
+	}
+}
+
+// find missing and failed containers in range defined by --from and --to flags
+func findGaps(c *cli.Context, ledger app.IContainerLedger) ([]*app.ContainerGap, error) {
+	from := c.Uint(flagFrom)
+	to := c.Uint(flagTo)
+	if to == 0 {
+		stateStore, err := factory.GetAppStateStore()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		state, err := stateStore.Get(providerKey)
+		if err != nil {
+			return nil, errors.Annotate(err, "can't get app state")
+		} else if state.LatestQueuedContainer == nil {
+			return nil, errors.New("nothing queued yet, specify --to")
+		}
+		to = state.LatestQueuedContainer.Uint()
+	}
+	if from > to {
+		return nil, errors.Errorf("wrong range, from=%d, to=%d", from, to)
+	}
+
+	finder := app.NewGapFinder(from, to)
+	//container waiting for retry stays processing, so it's stale after timeout of attempt and delay before the next one
+	jconf := factory.AppConfig.Queue.GetJobConfig(job.JobTypeContainerProcess)
+	timeout := app.DefaultJobTimeout
+	if jconf.TimeoutSec > 0 {
+		timeout = time.Duration(jconf.TimeoutSec) * time.Second
+	}
+	finder.SetProcessingTimeout(time.Now(), timeout+jconf.MaxRetryDelay())
+	err := ledger.Walk(c.Context, providerKey, from, to, func(record *app.ContainerRecord) error {
+		finder.Add(record)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't walk container ledger")
+	}
+	return finder.Gaps(), nil
+}
+
+func CmdGaps() *cli.Command {
+
+	return &cli.Command{
+		Name:  "gaps",
+		Usage: "find missing and failed container ranges in container ledger",
+		Flags: []cli.Flag{
+			cliFlags.From,
+			cliFlags.To,
+		},
+		Action: func(c *cli.Context) error {
+
+			ledger, err := factory.GetContainerLedger()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			gaps, err := findGaps(c, ledger)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			total := uint(0)
+			for _, gap := range gaps {
+				fmt.Printf("%d\t%d\t%d\t%s\n", gap.From, gap.To, gap.Len(), gap.Status)
+				total += gap.Len()
+			}
+			logrus.WithFields(logrus.Fields{
+				"gaps":       len(gaps),
+				"containers": total,
+			}).Info("gaps found")
+
+			return nil
+		},
+	}
+}
+
+func CmdClones() *cli.Command {
+
+	return &cli.Command{
+		Name:  "clones",
+		Usage: "report clone families: items with the same CodeHash, first deployed item and deployments per day",
+		Flags: []cli.Flag{
+			cliFlags.Limit,
+			cliFlags.MinCount,
+		},
+		Action: func(c *cli.Context) error {
+
+			limit := c.Uint(flagLimit)
+			if limit == 0 {
+				return errors.New("Limit must be greater than 0")
+			}
+			if !provider.NewItem("test").HasAutosetField("CodeHash") {
+				return errors.Errorf("items of provider=%s have no CodeHash", providerKey)
+			}
+
+			repository, err := factory.GetItemRepository()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			groups, err := repository.GetGroups(c.Context, provider, "CodeHash", []string{"Block", "Timestamp"}, c.Uint(flagMinCount), limit)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			clones := 0
+			for _, group := range groups {
+				first := group.Items[0]
+				block, _ := reflections.GetField(first, "Block")
+				fmt.Printf("%v\t%d\t%s\t%v\t%s\n", group.Value, group.Count, first.GetId().Id, block, getDeployTime(first).Format(time.RFC3339))
+
+				//timeline: deployments per day, items are ordered by block
+				day, count := "", 0
+				for _, item := range group.Items {
+					itemDay := getDeployTime(item).Format("2006-01-02")
+					if itemDay != day && count > 0 {
+						fmt.Printf("\t%s\t%d\n", day, count)
+						count = 0
+					}
+					day = itemDay
+					count++
+				}
+				fmt.Printf("\t%s\t%d\n", day, count)
+				clones += group.Count
+			}
+			logrus.WithFields(logrus.Fields{
+				"families": len(groups),
+				"items":    clones,
+			}).Info("clone families found")
+
+			return nil
+		},
+	}
+}
+
+// Timestamp property of item (seconds), zero time if item has no timestamp
+func getDeployTime(item app.IItem) time.Time {
+	value, err := reflections.GetField(item, "Timestamp")
+	if err != nil {
+		return time.Time{}
+	}
+	timestamp, _ := value.(uint32)
+	return time.Unix(int64(timestamp), 0).UTC()
+}
+
+func CmdBackfill() *cli.Command {
+
+	return &cli.Command{
+		Name:  "backfill",
+		Usage: "queue missing and failed containers found in container ledger",
+		Flags: []cli.Flag{
+			cliFlags.From,
+			cliFlags.To,
+			cliFlags.Limit,
+		},
+		Action: func(c *cli.Context) error {
+
+			limit := c.Uint(flagLimit)
+			if limit == 0 {
+				return errors.New("Limit must be greater than 0")
+			}
+
+			ledger, err := factory.GetContainerLedger()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			gaps, err := findGaps(c, ledger)
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			jobQueue, err := factory.GetJobQueue()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			//containers in queue already (e.g. waiting for retry) are skipped, but counted in limit
+			queued, skipped := uint(0), uint(0)
+			for _, gap := range gaps {
+				for _, container := range gap.Containers(limit - queued) {
+					info, err := job.QueueContainer(c.Context, providerKey, jobQueue, ledger, container)
+					if err != nil {
+						return errors.Trace(err)
+					} else if info.Duplicate {
+						skipped++
+					}
+					queued++
+				}
+				if queued >= limit {
+					break
+				}
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"number":  queued - skipped,
+				"skipped": skipped,
+			}).Info("containers queued")
+
+			return nil
+		},
+	}
+}
+
+func CmdFollow() *cli.Command {
+
+	return &cli.Command{
+		Name:  "follow",
+		Usage: "poll provider for new containers and queue them until SIGINT/SIGTERM (replaces cron for queue-container-process)",
+		Flags: []cli.Flag{
+			cliFlags.Limit,
+			cliFlags.Cursor,
+			cliFlags.Interval,
+			cliFlags.MaxDepth,
+		},
+		Action: func(c *cli.Context) error {
+
+			//follow settings from config, flags override them
+			pconf, err := appConfig.GetProviderConfigByKey(providerKey)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			follow, err := pconf.GetFollowConfig()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			interval := time.Duration(follow.IntervalSec) * time.Second
+			if c.IsSet(flagInterval) {
+				interval = c.Duration(flagInterval)
+			}
+			if c.IsSet(flagLimit) {
+				follow.Limit = c.Uint(flagLimit)
+			}
+			if c.IsSet(flagMaxDepth) {
+				follow.MaxQueueDepth = c.Int(flagMaxDepth)
+			}
+			if interval <= 0 || follow.Limit == 0 {
+				return errors.Errorf("interval and limit must be greater than 0, interval=%s, limit=%d", interval, follow.Limit)
+			}
+
+			stateStore, err := factory.GetAppStateStore()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			ledger, err := factory.GetContainerLedger()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			jobQueue, err := factory.GetJobQueue()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			//cancelled on SIGINT/SIGTERM
+			ctx := c.Context
+
+			cursor := c.String(flagCursor)
+			logrus.WithFields(logrus.Fields{
+				"provider":  providerKey,
+				"cursor":    cursor,
+				"interval":  interval,
+				"limit":     follow.Limit,
+				"max_depth": follow.MaxQueueDepth,
+			}).Info("follow started")
+
+			for {
+				_, err := job.FollowPoll(ctx, providerKey, provider, jobQueue, ledger, stateStore, cursor, follow)
+				if err != nil {
+					//provider or queue may be unavailable for a while, try again on next poll
+					logrus.WithError(err).Error("follow poll failed")
+				}
+
+				select {
+				case <-ctx.Done():
+					logrus.WithFields(logrus.Fields{
+						"provider": providerKey,
+					}).Info("follow stopped")
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+}
+
+func CmdCrawl() *cli.Command {
+
+	return &cli.Command{
+		Name:  "crawl",
+		Usage: "process range of containers and their properties in this process using memory queue, Redis isn't needed",
+		Flags: []cli.Flag{
+			cliFlags.From,
+			cliFlags.To,
+			cliFlags.Workers,
+		},
+		Action: func(c *cli.Context) error {
+
+			from := c.Uint(flagFrom)
+			to := c.Uint(flagTo)
+			if !c.IsSet(flagTo) || from > to {
+				return errors.Errorf("wrong range, from=%d, to=%d (--to is required)", from, to)
+			}
+
+			var startAfter *app.ItemsContainer
+			if from > 0 {
+				startAfter = app.NewItemsContainer([]string{strconv.Itoa(int(from - 1))})
+			}
+			list, err := provider.GetContainersList(c.Context, to-from+1, startAfter)
+			if err != nil {
+				return errors.Annotate(err, "can't get containers list")
+			}
+
+			ledger, err := factory.GetContainerLedger()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			jobQueue, err := factory.NewMemoryJobQueue()
+			if err != nil {
+				return errors.Trace(err)
+			}
+
+			//cursor isn't moved, crawl is independent from queue-container-process and follow
+			queued, err := job.QueueContainers(c.Context, providerKey, jobQueue, ledger, nil, nil, list)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			logrus.WithFields(logrus.Fields{
+				"number": queued,
+			}).Info("containers queued")
+
+			return processJobs(c.Context, jobQueue, c.Uint(flagWorkers), job.JobTypeContainerProcess, job.JobTypePropertySet+":*")
+		},
+	}
+}
+
+/*
+Process jobs of memory queue until queues are empty.
+Queue is closed when context is done (SIGINT/SIGTERM), unprocessed jobs are lost then.
+*/
+func processJobs(ctx context.Context, jobQueue app.IJobQueue, workersNum uint, queues ...string) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			jobQueue.Close()
+		case <-done:
+		}
+	}()
+
+	err := jobQueue.Process(strings.Join(queues, ","), workersNum)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ctx.Err())
+}
@@ -0,0 +1,262 @@
+package evm
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/jsonrpc"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type EvmApiConfig struct {
+	HttpUrl    string
+	TimeoutSec int
+}
+
+type EvmConfig struct {
+	Id      string
+	ChainId string
+	Api     *EvmApiConfig
+}
+
+// provider type in config.json
+const ProviderType = "evm"
+
+type EvmBlockchain struct {
+	Client *jsonrpc.Client
+	Config *EvmConfig
+}
+
+var _ app.IItemProvider = (*EvmBlockchain)(nil)
+
+// fields of eth_getBlockByNumber response we need, numbers are hex encoded
+type rpcBlock struct {
+	Number       string            `json:"number"`
+	Timestamp    string            `json:"timestamp"`
+	Transactions []*rpcTransaction `json:"transactions"`
+}
+
+type rpcTransaction struct {
+	Hash  string  `json:"hash"`
+	From  string  `json:"from"`
+	To    *string `json:"to"`
+	Input string  `json:"input"`
+}
+
+type rpcReceipt struct {
+	TransactionHash string  `json:"transactionHash"`
+	ContractAddress *string `json:"contractAddress"`
+	//empty in receipts of pre-Byzantium blocks, they have state root instead
+	Status string `json:"status"`
+}
+
+const receiptStatusFailed = "0x0"
+
+func init() {
+	app.RegisterItemProviderType(ProviderType, NewEvmBlockchainFromConfig)
+}
+
+func (c *EvmConfig) Validate() error {
+	if c.Id == "" {
+		return errors.New("Id is not defined")
+	} else if c.ChainId == "" {
+		return errors.New("ChainId is not defined")
+	} else if c.Api == nil || c.Api.HttpUrl == "" {
+		return errors.New("Api.HttpUrl is not defined")
+	}
+	return nil
+}
+
+func NewEvmBlockchainFromConfig(pconf *app.ItemProviderConfig) (app.IItemProvider, error) {
+	econfig := EvmConfig{}
+	err := pconf.Decode(&econfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = econfig.Validate()
+	if err != nil {
+		return nil, errors.Annotate(err, "invalid evm config")
+	}
+	return NewEvmBlockchain(&econfig), nil
+}
+
+func NewEvmBlockchain(config *EvmConfig) *EvmBlockchain {
+	timeout := time.Duration(config.Api.TimeoutSec) * time.Second
+	return &EvmBlockchain{
+		Client: jsonrpc.NewClient(config.Api.HttpUrl, timeout),
+		Config: config,
+	}
+}
+
+func (e *EvmBlockchain) PrepareItemsArray(limit uint) []app.IItem {
+	result := make([]app.IItem, limit)
+	for i := uint(0); i < limit; i++ {
+		contract := &EvmContract{}
+		contract.Item = app.NewItem(e.Config.Id, e.Config.ChainId, "")
+		result[i] = contract
+	}
+	return result
+}
+
+func (e *EvmBlockchain) NewItem(id string) app.IItem {
+	contract := &EvmContract{}
+	contract.Item = app.NewItem(e.Config.Id, e.Config.ChainId, strings.ToLower(id))
+	contract.RegisterAutosetters()
+	return contract
+}
+
+func (e *EvmBlockchain) Close() error {
+	return nil
+}
+
+func (e *EvmBlockchain) GetContainersList(ctx context.Context, blocksNumber uint, startAfter *app.ItemsContainer) ([]*app.ItemsContainer, error) {
+	var startBlock uint
+	if blocksNumber == 0 {
+		return []*app.ItemsContainer{}, nil
+	} else if startAfter == nil {
+		startBlock = 0
+	} else {
+		startBlock = startAfter.Uint() + 1
+	}
+
+	latestBlock, err := e.GetLatestBlockId(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("can't get latest block id")
+		return nil, errors.Annotate(err, "can't get latest block id")
+	} else if startBlock > latestBlock {
+		//nothing new on chain yet
+		return []*app.ItemsContainer{}, nil
+	}
+
+	maxNumber := latestBlock - startBlock + 1
+	if blocksNumber > maxNumber {
+		blocksNumber = maxNumber
+	}
+
+	result := make([]*app.ItemsContainer, 0)
+	for i := startBlock; i < startBlock+blocksNumber; i++ {
+		blockId := strconv.Itoa(int(i))
+		container := app.NewItemsContainer([]string{blockId})
+		result = append(result, container)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"start_block":        startBlock,
+		"number_blocks":      blocksNumber,
+		"latest_chain_block": latestBlock,
+		"got_blocks":         len(result),
+	}).Debug("GetContainersList")
+
+	return result, nil
+}
+
+func (e *EvmBlockchain) FetchContainerItems(ctx context.Context, container *app.ItemsContainer) ([]app.IItem, error) {
+	idBlock := container.Uint()
+
+	block, err := e.getBlock(ctx, idBlock)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't fetch container items, block=%d", idBlock)
+	} else if block == nil {
+		logrus.WithFields(logrus.Fields{
+			"api_call": "eth_getBlockByNumber",
+			"block_id": idBlock,
+		}).Debug("block not found")
+		return []app.IItem{}, nil
+	}
+
+	timestamp, err := hexToUint64(block.Timestamp)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't parse timestamp for block=%d", idBlock)
+	}
+
+	contractsDeployed := make([]app.IItem, 0)
+	for _, tx := range block.Transactions {
+		if !e.IsContractCreation(tx) {
+			continue
+		}
+
+		receipt, err := e.getTransactionReceipt(ctx, tx.Hash)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get transaction receipt, txid=%s", tx.Hash)
+		} else if receipt == nil || receipt.ContractAddress == nil || receipt.Status == receiptStatusFailed {
+			//failed deploy, there is no contract
+			continue
+		}
+
+		code, err := e.getCode(ctx, *receipt.ContractAddress, idBlock)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get contract code, address=%s", *receipt.ContractAddress)
+		}
+
+		//init contract object
+		contract := e.NewItem(*receipt.ContractAddress)
+		contract.(*EvmContract).Creator = strings.ToLower(tx.From)
+		contract.(*EvmContract).Block = idBlock
+		contract.(*EvmContract).Txid = tx.Hash
+		contract.(*EvmContract).Bytecode = code
+		contract.(*EvmContract).Timestamp = uint32(timestamp)
+
+		contractsDeployed = append(contractsDeployed, contract)
+	}
+
+	return contractsDeployed, nil
+}
+
+// contract creation transaction has no recipient
+func (e *EvmBlockchain) IsContractCreation(tx *rpcTransaction) bool {
+	return tx.To == nil || *tx.To == ""
+}
+
+func (e *EvmBlockchain) GetLatestBlockId(ctx context.Context) (uint, error) {
+	var result string
+	err := e.Client.Call(ctx, "eth_blockNumber", &result)
+	if err != nil {
+		return 0, errors.Annotate(err, "can't get blockhain height")
+	}
+	height, err := hexToUint64(result)
+	if err != nil {
+		return 0, errors.Annotate(err, "can't parse blockhain height")
+	}
+	return uint(height), nil
+}
+
+func (e *EvmBlockchain) getBlock(ctx context.Context, idBlock uint) (*rpcBlock, error) {
+	var block *rpcBlock
+	//true means full transaction objects instead of hashes
+	err := e.Client.Call(ctx, "eth_getBlockByNumber", &block, uintToHex(idBlock), true)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't get block")
+	}
+	return block, nil
+}
+
+func (e *EvmBlockchain) getTransactionReceipt(ctx context.Context, txid string) (*rpcReceipt, error) {
+	var receipt *rpcReceipt
+	err := e.Client.Call(ctx, "eth_getTransactionReceipt", &receipt, txid)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't get transaction receipt")
+	}
+	return receipt, nil
+}
+
+// runtime bytecode of contract at given block
+func (e *EvmBlockchain) getCode(ctx context.Context, address string, idBlock uint) (string, error) {
+	var code string
+	err := e.Client.Call(ctx, "eth_getCode", &code, address, uintToHex(idBlock))
+	if err != nil {
+		return "", errors.Annotate(err, "can't get code")
+	}
+	return code, nil
+}
+
+func hexToUint64(value string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+}
+
+func uintToHex(value uint) string {
+	return "0x" + strconv.FormatUint(uint64(value), 16)
+}
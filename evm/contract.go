@@ -0,0 +1,67 @@
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"purrproof/smartcrawl/app"
+	"strings"
+
+	"github.com/juju/errors"
+	"golang.org/x/crypto/sha3"
+)
+
+type EvmContract struct {
+	*app.Item `bson:"inline"`
+	//from app.Item:
+	//ProvName              string //Ethereum
+	//ProvBranch           string //ChainId
+	//Id                  string //Address
+	Creator   string `bson:"creator"`
+	Block     uint   `bson:"block"`
+	Txid      string `bson:"txid"`
+	Bytecode  string `bson:"bytecode"`
+	Timestamp uint32 `bson:"timestamp"`
+	//realtime computed properties
+	SizeBytes int    `bson:"sizebytes"`
+	CodeHash  string `bson:"codehash"`
+}
+
+var _ app.IItem = (*EvmContract)(nil)
+
+func (c *EvmContract) RegisterAutosetters() error {
+	c.RegisterRealtimeAutosetter("SizeBytes", c.AutosetSizeBytes)
+	c.RegisterRealtimeAutosetter("CodeHash", c.AutosetCodeHash)
+	return nil
+}
+
+func (c *EvmContract) bytecodeBytes() ([]byte, error) {
+	code, err := hex.DecodeString(strings.TrimPrefix(c.Bytecode, "0x"))
+	if err != nil {
+		return nil, errors.Annotate(err, "can't decode bytecode")
+	}
+	return code, nil
+}
+
+/* ========== realtime computed properties ========== */
+
+// size of runtime bytecode, not of its hex representation
+func (c *EvmContract) AutosetSizeBytes(ctx context.Context) error {
+	code, err := c.bytecodeBytes()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.SizeBytes = len(code)
+	return nil
+}
+
+// keccak256 of runtime bytecode, the same value as EXTCODEHASH returns
+func (c *EvmContract) AutosetCodeHash(ctx context.Context) error {
+	code, err := c.bytecodeBytes()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(code)
+	c.CodeHash = "0x" + hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
@@ -3,25 +3,38 @@ package factory
 import (
 	"purrproof/smartcrawl/app"
 	"purrproof/smartcrawl/asynq"
+	"purrproof/smartcrawl/memory"
 	"purrproof/smartcrawl/mongo"
 
 	"github.com/juju/errors"
 )
 
 type Factory struct {
-	AppConfig      *app.AppConfig
-	JobQueue       app.IJobQueue
-	AppStateStore  app.IAppStateStore
-	ItemRepository app.IItemRepository
-	ItemProvider   map[string]app.IItemProvider
-	deferred       []func() error
+	AppConfig       *app.AppConfig
+	JobQueue        app.IJobQueue
+	AppStateStore   app.IAppStateStore
+	Ledger          app.IContainerLedger
+	CallRepository  app.ICallRepository
+	EventRepository app.IEventRepository
+	SnapshotStore   app.ISnapshotStore
+	ItemRepository  app.IItemRepository
+	ItemProvider    map[string]app.IItemProvider
+	//jobs are processed by this process in memory queue (crawl, exec-*), so rate limit isn't shared through Redis
+	LocalJobs bool
+	//lowercase keys of providers with TrackCalls enabled
+	trackCalls map[string]bool
+	//lowercase keys of providers with ExtractEvents enabled
+	extractEvents map[string]bool
+	deferred      []func() error
 }
 
 func NewFactory(appConfig *app.AppConfig) *Factory {
 	factory := &Factory{
-		AppConfig:    appConfig,
-		ItemProvider: make(map[string]app.IItemProvider, 0),
-		deferred:     make([]func() error, 0),
+		AppConfig:     appConfig,
+		ItemProvider:  make(map[string]app.IItemProvider, 0),
+		trackCalls:    make(map[string]bool, 0),
+		extractEvents: make(map[string]bool, 0),
+		deferred:      make([]func() error, 0),
 	}
 	return factory
 }
@@ -36,6 +49,10 @@ func (f *Factory) RunDeferred() {
 	}
 }
 
+/*
+Persistent queue shared by producer and worker commands.
+Producers advance cursors and mark ledger after enqueue, so jobs must outlive the process.
+*/
 func (f *Factory) GetJobQueue() (app.IJobQueue, error) {
 	if f.JobQueue != nil {
 		return f.JobQueue, nil
@@ -49,6 +66,16 @@ func (f *Factory) GetJobQueue() (app.IJobQueue, error) {
 	return queue, nil
 }
 
+// in-process queue regardless of config, for commands which process jobs themselves
+func (f *Factory) NewMemoryJobQueue() (app.IJobQueue, error) {
+	queue, err := memory.NewJobQueue(f.AppConfig.Queue, f.HandleJobPayload)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't initialize memory job queue")
+	}
+	f.Defer(queue.Close)
+	return queue, nil
+}
+
 func (f *Factory) GetItemRepository() (app.IItemRepository, error) {
 	if f.ItemRepository != nil {
 		return f.ItemRepository, nil
@@ -74,3 +101,55 @@ func (f *Factory) GetAppStateStore() (app.IAppStateStore, error) {
 	f.Defer(f.AppStateStore.Close)
 	return stateStore, nil
 }
+
+func (f *Factory) GetContainerLedger() (app.IContainerLedger, error) {
+	if f.Ledger != nil {
+		return f.Ledger, nil
+	}
+	ledger, err := mongo.NewContainerLedger(f.AppConfig.Storage)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't initialize container ledger")
+	}
+	f.Ledger = ledger
+	f.Defer(f.Ledger.Close)
+	return ledger, nil
+}
+
+func (f *Factory) GetCallRepository() (app.ICallRepository, error) {
+	if f.CallRepository != nil {
+		return f.CallRepository, nil
+	}
+	repository, err := mongo.NewCallRepository(f.AppConfig.Storage)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't initialize call repository")
+	}
+	f.CallRepository = repository
+	f.Defer(f.CallRepository.Close)
+	return repository, nil
+}
+
+func (f *Factory) GetEventRepository() (app.IEventRepository, error) {
+	if f.EventRepository != nil {
+		return f.EventRepository, nil
+	}
+	repository, err := mongo.NewEventRepository(f.AppConfig.Storage)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't initialize event repository")
+	}
+	f.EventRepository = repository
+	f.Defer(f.EventRepository.Close)
+	return repository, nil
+}
+
+func (f *Factory) GetSnapshotStore() (app.ISnapshotStore, error) {
+	if f.SnapshotStore != nil {
+		return f.SnapshotStore, nil
+	}
+	store, err := mongo.NewSnapshotStore(f.AppConfig.Storage)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't initialize snapshot store")
+	}
+	f.SnapshotStore = store
+	f.Defer(f.SnapshotStore.Close)
+	return store, nil
+}
@@ -1,9 +1,11 @@
 package factory
 
 import (
+	"context"
 	"encoding/json"
 	"purrproof/smartcrawl/app"
 	"purrproof/smartcrawl/app/job"
+	"strings"
 
 	"github.com/juju/errors"
 )
@@ -44,15 +46,64 @@ func (f *Factory) UnmarshalJob(payload []byte) (app.IJob, error) {
 		return nil, errors.Annotatef(err, "can't get item repository for job name=%s", job.JobTypeContainerProcess)
 	}
 	jobres.SetItemRepository(repository)
+	//container ledger is needed only for container jobs
+	if containerJob, ok := jobres.(*job.JobContainerProcess); ok {
+		ledger, err := f.GetContainerLedger()
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get container ledger for job name=%s", job.JobTypeContainerProcess)
+		}
+		containerJob.SetContainerLedger(ledger)
+		err = f.setCallRepository(containerJob)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't set call repository for job name=%s", job.JobTypeContainerProcess)
+		}
+		err = f.setEventRepository(containerJob)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't set event repository for job name=%s", job.JobTypeContainerProcess)
+		}
+	}
+	if propertyJob, ok := jobres.(*job.JobPropertySet); ok {
+		store, err := f.GetSnapshotStore()
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get snapshot store for job name=%s", job.JobTypePropertySet)
+		}
+		propertyJob.SetSnapshotStore(store)
+	}
 	return jobres, nil
 }
 
-func (f *Factory) HandleJobPayload(payload []byte) ([]app.IJob, error) {
+// calls are tracked if Providers.<key>.TrackCalls is enabled, see GetProviderByKey
+func (f *Factory) setCallRepository(containerJob *job.JobContainerProcess) error {
+	if !f.trackCalls[strings.ToLower(containerJob.GetProviderKey())] {
+		return nil
+	}
+	repository, err := f.GetCallRepository()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	containerJob.SetCallRepository(repository)
+	return nil
+}
+
+// events are extracted if Providers.<key>.ExtractEvents is enabled, see GetProviderByKey
+func (f *Factory) setEventRepository(containerJob *job.JobContainerProcess) error {
+	if !f.extractEvents[strings.ToLower(containerJob.GetProviderKey())] {
+		return nil
+	}
+	repository, err := f.GetEventRepository()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	containerJob.SetEventRepository(repository)
+	return nil
+}
+
+func (f *Factory) HandleJobPayload(ctx context.Context, payload []byte) ([]app.IJob, error) {
 	job, err := f.UnmarshalJob(payload)
 	if err != nil {
 		return nil, errors.Annotate(err, "can't unmarshal job")
 	}
-	newJobs, err := job.Execute()
+	newJobs, err := job.Execute(ctx)
 	if err != nil {
 		return newJobs, errors.Annotate(err, "can't execute job")
 	}
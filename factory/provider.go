@@ -2,11 +2,15 @@ package factory
 
 import (
 	"purrproof/smartcrawl/app"
-	"purrproof/smartcrawl/zilliqa"
+	"purrproof/smartcrawl/memory"
+	"purrproof/smartcrawl/redis"
 	"strings"
 
+	//provider packages register their types in app registry
+	_ "purrproof/smartcrawl/evm"
+	_ "purrproof/smartcrawl/zilliqa"
+
 	"github.com/juju/errors"
-	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,21 +25,93 @@ func (f *Factory) GetProviderByKey(provKey string) (app.IItemProvider, error) {
 	if err != nil {
 		return nil, errors.Annotatef(err, "can't get provider config for id=%s", provKey)
 	}
-	var itemProv app.IItemProvider
-	switch provKeyLower {
-	case "zilmain":
-		zconfig := zilliqa.ZilliqaConfig{}
-		mapstructure.Decode(pconf, &zconfig)
-		itemProv = zilliqa.NewZilliqaBlockchain(&zconfig)
-	default:
-		return nil, errors.Errorf("unknown provider: %s", provKey)
+
+	itemProv, err := app.NewItemProvider(pconf)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't initialize provider: %s", provKey)
+	}
+
+	err = f.setRateLimiter(provKeyLower, pconf, itemProv)
+	if err != nil {
+		itemProv.Close()
+		return nil, errors.Annotatef(err, "can't set rate limit of provider: %s", provKey)
+	}
+
+	err = f.setCallTracking(provKeyLower, pconf, itemProv)
+	if err != nil {
+		itemProv.Close()
+		return nil, errors.Annotatef(err, "can't set call tracking of provider: %s", provKey)
+	}
+
+	err = f.setEventExtraction(provKeyLower, pconf, itemProv)
+	if err != nil {
+		itemProv.Close()
+		return nil, errors.Annotatef(err, "can't set event extraction of provider: %s", provKey)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"provider": provKey,
+		"type":     pconf.GetType(),
 	}).Debug("provider initialized")
 
 	f.ItemProvider[provKeyLower] = itemProv
 	f.Defer(f.ItemProvider[provKeyLower].Close)
 	return itemProv, nil
 }
+
+/*
+Limiter of Providers.<key>.RateLimit, it's shared by workers through Redis of job queue.
+Without queue config or with LocalJobs the process is limited only.
+*/
+func (f *Factory) setRateLimiter(provKey string, pconf *app.ItemProviderConfig, prov app.IItemProvider) error {
+	rconf, err := pconf.GetRateLimitConfig()
+	if err != nil {
+		return errors.Trace(err)
+	} else if rconf == nil {
+		return nil
+	}
+	limited, ok := prov.(app.IRateLimitedProvider)
+	if !ok {
+		return errors.Errorf("provider type=%s doesn't support rate limit", pconf.GetType())
+	}
+
+	var limiter app.IRateLimiter
+	if f.AppConfig.Queue == nil || f.LocalJobs {
+		limiter = memory.NewRateLimiter(rconf)
+	} else {
+		limiter = redis.NewRateLimiter(f.AppConfig.Queue, provKey, rconf)
+	}
+	limited.SetRateLimiter(limiter)
+	f.Defer(limiter.Close)
+	return nil
+}
+
+// Providers.<key>.TrackCalls requires provider which finds calls, calls are saved by container jobs
+func (f *Factory) setCallTracking(provKey string, pconf *app.ItemProviderConfig, prov app.IItemProvider) error {
+	enabled, err := pconf.IsCallTrackingEnabled()
+	if err != nil {
+		return errors.Trace(err)
+	} else if !enabled {
+		return nil
+	}
+	if _, ok := prov.(app.IActivityProvider); !ok {
+		return errors.NotSupportedf("call tracking by provider type=%s", pconf.GetType())
+	}
+	f.trackCalls[provKey] = true
+	return nil
+}
+
+// Providers.<key>.ExtractEvents requires provider which finds events, events are saved by container jobs
+func (f *Factory) setEventExtraction(provKey string, pconf *app.ItemProviderConfig, prov app.IItemProvider) error {
+	enabled, err := pconf.IsEventExtractionEnabled()
+	if err != nil {
+		return errors.Trace(err)
+	} else if !enabled {
+		return nil
+	}
+	if _, ok := prov.(app.IEventProvider); !ok {
+		return errors.NotSupportedf("event extraction by provider type=%s", pconf.GetType())
+	}
+	f.extractEvents[provKey] = true
+	return nil
+}
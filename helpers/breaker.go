@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Circuit breaker settings of provider API, e.g. Providers.<key>.Api.CircuitBreaker in config.json.
+Zero values mean defaults.
+*/
+type CircuitBreakerConfig struct {
+	//number of failed calls in a row which opens breaker, -1 disables it (Retry-After pauses are still applied)
+	FailureThreshold int
+	//pause of all calls when breaker is open
+	OpenSec int
+}
+
+const (
+	defaultBreakerFailureThreshold = 10
+	defaultBreakerOpenSec          = 30
+)
+
+/*
+CircuitBreaker pauses all calls to endpoint after sustained failures (transient and rate limited errors).
+While breaker is open, calls wait, so workers don't hammer node which is down.
+After pause calls go again, the next failure opens breaker at once, success closes it.
+*/
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	openFor   time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+/*
+Breakers are shared by providers with the same endpoint and breaker settings, so outage pauses all of them.
+Providers with other settings of the same endpoint get own breaker, settings of one provider don't depend on
+which provider was created first.
+*/
+var (
+	breakers   = map[breakerKey]*CircuitBreaker{}
+	breakersMu sync.Mutex
+)
+
+type breakerKey struct {
+	url       string
+	threshold int
+	openFor   time.Duration
+}
+
+// breaker of endpoint url with settings of conf
+func GetCircuitBreaker(url string, conf *CircuitBreakerConfig) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	candidate := NewCircuitBreaker(url, conf)
+	key := breakerKey{url: url, threshold: candidate.threshold, openFor: candidate.openFor}
+	if breaker, ok := breakers[key]; ok {
+		return breaker
+	}
+	for other := range breakers {
+		if other.url == url {
+			logrus.WithFields(logrus.Fields{
+				"name":              url,
+				"failure_threshold": candidate.threshold,
+				"open_for":          candidate.openFor,
+				"other_threshold":   other.threshold,
+				"other_open_for":    other.openFor,
+			}).Warning("endpoint has breaker with other settings, breakers aren't shared")
+			break
+		}
+	}
+	breakers[key] = candidate
+	return candidate
+}
+
+func NewCircuitBreaker(name string, conf *CircuitBreakerConfig) *CircuitBreaker {
+	config := CircuitBreakerConfig{}
+	if conf != nil {
+		config = *conf
+	}
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if config.OpenSec <= 0 {
+		config.OpenSec = defaultBreakerOpenSec
+	}
+	return &CircuitBreaker{
+		name:      name,
+		threshold: config.FailureThreshold,
+		openFor:   time.Duration(config.OpenSec) * time.Second,
+	}
+}
+
+// time left until breaker closes, 0 if it's closed
+func (b *CircuitBreaker) OpenFor() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	left := time.Until(b.openUntil)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// opens breaker regardless of failures count, e.g. when health check failed
+func (b *CircuitBreaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.openUntil.After(time.Now()) {
+		logrus.WithField("name", b.name).Warning("circuit breaker is open, health check failed")
+	}
+	b.openUntil = time.Now().Add(b.openFor)
+}
+
+// pause is requested by node (Retry-After), it's applied to all calls even if threshold isn't reached
+func (b *CircuitBreaker) Failure(pause time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	now := time.Now()
+	until := b.openUntil
+	if b.threshold > 0 && b.failures >= b.threshold {
+		until = now.Add(b.openFor)
+		//half-open after pause: one more failure opens breaker again
+		b.failures = b.threshold - 1
+	}
+	if pause > 0 && now.Add(pause).After(until) {
+		until = now.Add(pause)
+	}
+	if until.After(b.openUntil) {
+		if !b.openUntil.After(now) {
+			logrus.WithFields(logrus.Fields{
+				"name":  b.name,
+				"pause": until.Sub(now),
+			}).Warning("circuit breaker is open, calls are paused")
+		}
+		b.openUntil = until
+	}
+}
@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// endpoint of provider API, e.g. item of Providers.<key>.Api.Endpoints in config.json
+type EndpointConfig struct {
+	HttpUrl string
+	//share of calls relative to other endpoints, 1 by default
+	Weight int
+}
+
+type EndpointStats struct {
+	Calls     uint64
+	Errors    uint64
+	Latency   time.Duration
+	LastError string
+}
+
+func (s EndpointStats) AvgLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Calls)
+}
+
+type Endpoint struct {
+	Url     string
+	Weight  int
+	Client  *jsonrpc.Client
+	Breaker *CircuitBreaker
+
+	//smooth weighted round-robin state, guarded by pool
+	current int
+
+	mu    sync.Mutex
+	stats EndpointStats
+}
+
+func (e *Endpoint) Stats() EndpointStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+func (e *Endpoint) record(latency time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stats.Calls++
+	e.stats.Latency += latency
+	if err != nil {
+		e.stats.Errors++
+		e.stats.LastError = err.Error()
+	}
+}
+
+/*
+EndpointPool spreads calls over endpoints by weight (smooth weighted round-robin).
+Endpoint with open circuit breaker is skipped, so calls fail over to other endpoints,
+when all of them are open calls wait for the first one to close.
+*/
+type EndpointPool struct {
+	name      string
+	endpoints []*Endpoint
+	classify  func(err error) app.ErrorKind
+	limiter   app.IRateLimiter
+	mu        sync.Mutex
+}
+
+// classify defines error kind, transient and rate limited errors are failures of endpoint
+func NewEndpointPool(name string, confs []EndpointConfig, timeout time.Duration, breakerConf *CircuitBreakerConfig, classify func(err error) app.ErrorKind) (*EndpointPool, error) {
+	if len(confs) == 0 {
+		return nil, errors.New("no endpoints defined")
+	}
+	if classify == nil {
+		classify = GetErrorKind
+	}
+	pool := &EndpointPool{
+		name:     name,
+		classify: classify,
+	}
+	for i, conf := range confs {
+		if conf.HttpUrl == "" {
+			return nil, errors.Errorf("HttpUrl of endpoint %d is not defined", i)
+		}
+		weight := conf.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		pool.endpoints = append(pool.endpoints, &Endpoint{
+			Url:     conf.HttpUrl,
+			Weight:  weight,
+			Client:  jsonrpc.NewClient(conf.HttpUrl, timeout),
+			Breaker: GetCircuitBreaker(conf.HttpUrl, breakerConf),
+		})
+	}
+	return pool, nil
+}
+
+// limiter is applied to all calls of pool, nil disables limit
+func (p *EndpointPool) SetRateLimiter(limiter app.IRateLimiter) {
+	p.limiter = limiter
+}
+
+func (p *EndpointPool) wait(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
+
+func (p *EndpointPool) Endpoints() []*Endpoint {
+	return p.endpoints
+}
+
+// picks endpoint for the next call, waits if breakers of all endpoints are open
+func (p *EndpointPool) Next(ctx context.Context) (*Endpoint, error) {
+	for {
+		endpoint, wait := p.pick()
+		if endpoint != nil {
+			return endpoint, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err := errors.Annotatef(ctx.Err(), "all endpoints are unavailable, pool=%s", p.name)
+			return nil, app.NewClassifiedError(app.ErrorKindTransient, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// returns endpoint or time until the first breaker closes
+func (p *EndpointPool) pick() (*Endpoint, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *Endpoint
+	var wait time.Duration
+	total := 0
+	for _, endpoint := range p.endpoints {
+		if openFor := endpoint.Breaker.OpenFor(); openFor > 0 {
+			if wait == 0 || openFor < wait {
+				wait = openFor
+			}
+			continue
+		}
+		endpoint.current += endpoint.Weight
+		total += endpoint.Weight
+		if best == nil || endpoint.current > best.current {
+			best = endpoint
+		}
+	}
+	if best != nil {
+		best.current -= total
+	}
+	return best, wait
+}
+
+/*
+Do calls fn with client of the next endpoint and records result in stats and breaker of endpoint.
+Retry-After of rate limited response pauses that endpoint only.
+fn should make one request, rate limit token is taken for it.
+*/
+func (p *EndpointPool) Do(ctx context.Context, fn func(ctx context.Context, client *jsonrpc.Client) error) error {
+	endpoint, err := p.Next(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = p.wait(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	started := time.Now()
+	err = fn(ctx, endpoint.Client)
+	if err != nil && ctx.Err() != nil {
+		//cancelled by caller, it isn't failure of endpoint
+		return err
+	}
+	endpoint.record(time.Since(started), err)
+	if err == nil {
+		endpoint.Breaker.Success()
+		return nil
+	}
+	if p.classify(err).IsRetryable() {
+		endpoint.Breaker.Failure(getRetryAfter(err))
+	} else {
+		//node responded, so it's alive
+		endpoint.Breaker.Success()
+	}
+	return errors.Annotatef(err, "endpoint=%s", endpoint.Url)
+}
+
+/*
+RunHealthCheck calls check for every endpoint each interval until ctx is done.
+Failed check opens breaker of endpoint, so calls go to other endpoints. Stats are logged after each round.
+*/
+func (p *EndpointPool) RunHealthCheck(ctx context.Context, interval time.Duration, check func(ctx context.Context, client *jsonrpc.Client) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, endpoint := range p.endpoints {
+			if err := p.wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logrus.WithField("pool", p.name).WithError(err).Warning("health check is skipped")
+				break
+			}
+			err := check(ctx, endpoint.Client)
+			if ctx.Err() != nil {
+				return
+			} else if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"pool":     p.name,
+					"endpoint": endpoint.Url,
+				}).WithError(err).Warning("health check failed")
+				endpoint.Breaker.Trip()
+			}
+		}
+		p.LogStats()
+	}
+}
+
+func (p *EndpointPool) LogStats() {
+	for _, endpoint := range p.endpoints {
+		stats := endpoint.Stats()
+		logrus.WithFields(logrus.Fields{
+			"pool":        p.name,
+			"endpoint":    endpoint.Url,
+			"weight":      endpoint.Weight,
+			"calls":       stats.Calls,
+			"errors":      stats.Errors,
+			"avg_latency": stats.AvgLatency(),
+			"open_for":    endpoint.Breaker.OpenFor(),
+			"last_error":  stats.LastError,
+		}).Info("endpoint stats")
+	}
+}
+
+func getRetryAfter(err error) time.Duration {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
@@ -1,15 +1,80 @@
 package helpers
 
-import "strings"
-
-func IsNetworkError(err error) bool {
-	if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "EOF") ||
-		strings.Contains(err.Error(), "connection reset by peer") ||
-		strings.Contains(err.Error(), "server closed idle connection") ||
-		strings.Contains(err.Error(), "504") ||
-		strings.Contains(err.Error(), "status code: 429. could not decode body to rpc response: invalid character '<' looking for beginning of value") ||
-		strings.Contains(err.Error(), "502") {
-		return true
-	}
-	return false
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/jsonrpc"
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// standard JSON-RPC 2.0 error codes
+const (
+	RpcParseError     = -32700
+	RpcInvalidRequest = -32600
+	RpcMethodNotFound = -32601
+	RpcInvalidParams  = -32602
+	RpcInternalError  = -32603
+)
+
+/*
+Kind of error returned by RPC call.
+Kind attached by provider (app.ClassifiedError) wins, otherwise it's derived from error type:
+transport errors, HTTP status of response, JSON-RPC error code.
+Provider specific codes and messages should be classified by provider before.
+*/
+func GetErrorKind(err error) app.ErrorKind {
+	if err == nil {
+		return app.ErrorKindUnknown
+	}
+	if kind := app.GetErrorKind(err); kind != app.ErrorKindUnknown {
+		return kind
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return GetHttpStatusKind(httpErr.StatusCode)
+	}
+
+	var rpcErr *jsonrpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case RpcParseError, RpcInvalidRequest, RpcMethodNotFound, RpcInvalidParams:
+			return app.ErrorKindPermanent
+		case RpcInternalError:
+			return app.ErrorKindTransient
+		}
+		return app.ErrorKindUnknown
+	}
+
+	//job timeout or shutdown, job may succeed next time
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return app.ErrorKindTransient
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return app.ErrorKindTransient
+	}
+
+	return app.ErrorKindUnknown
+}
+
+func GetHttpStatusKind(statusCode int) app.ErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return app.ErrorKindRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		return app.ErrorKindTransient
+	case statusCode >= 400:
+		return app.ErrorKindPermanent
+	default:
+		return app.ErrorKindUnknown
+	}
 }
@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"context"
+	"math/rand"
+	"purrproof/smartcrawl/app"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Retry settings of provider API, e.g. Providers.<key>.Api.Retry in config.json.
+Zero values mean defaults.
+*/
+type RetryConfig struct {
+	//attempts including the first one
+	MaxAttempts int
+	//delay before the first retry, doubled for each next one
+	DelayMs    int
+	MaxDelayMs int
+	//part of delay which is random, from 0 to 1
+	Jitter float64
+}
+
+const (
+	defaultRetryMaxAttempts = 5
+	defaultRetryDelayMs     = 1000
+	defaultRetryMaxDelayMs  = 30000
+	defaultRetryJitter      = 0.5
+)
+
+type RetryPolicy struct {
+	config   RetryConfig
+	classify func(err error) app.ErrorKind
+}
+
+/*
+conf may be nil.
+classify defines error kind, only transient and rate limited errors are retried.
+*/
+func NewRetryPolicy(conf *RetryConfig, classify func(err error) app.ErrorKind) *RetryPolicy {
+	config := RetryConfig{}
+	if conf != nil {
+		config = *conf
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if config.DelayMs <= 0 {
+		config.DelayMs = defaultRetryDelayMs
+	}
+	if config.MaxDelayMs <= 0 {
+		config.MaxDelayMs = defaultRetryMaxDelayMs
+	}
+	if config.Jitter <= 0 || config.Jitter > 1 {
+		config.Jitter = defaultRetryJitter
+	}
+	if classify == nil {
+		classify = GetErrorKind
+	}
+	return &RetryPolicy{
+		config:   config,
+		classify: classify,
+	}
+}
+
+/*
+Do calls fn until it succeeds, fails with not retryable error or attempts are over.
+Returned error has kind attached (see app.ClassifiedError), so caller can check e.g. not found errors.
+fields are added to log messages.
+Retry-After of endpoint is honored by fn, see EndpointPool.
+Retries are stopped if delay doesn't fit into deadline of ctx (e.g. job timeout).
+*/
+func (p *RetryPolicy) Do(ctx context.Context, fields logrus.Fields, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= p.config.MaxAttempts; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		} else if ctx.Err() != nil {
+			//cancelled by caller, it isn't failure of node
+			return errors.Trace(err)
+		}
+
+		kind := p.classify(err)
+		if kind != app.ErrorKindUnknown && app.GetErrorKind(err) != kind {
+			err = app.NewClassifiedError(kind, err)
+		}
+		if !kind.IsRetryable() {
+			return err
+		} else if attempt == p.config.MaxAttempts {
+			break
+		}
+
+		delay := p.Delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			//job would be cut off by its timeout while waiting, job retry takes over
+			return errors.Annotatef(err, "no time for retry before deadline, attempt=%d", attempt)
+		}
+		logrus.WithFields(fields).WithFields(logrus.Fields{
+			"attempt":    attempt,
+			"error_kind": kind.String(),
+			"delay":      delay,
+		}).Warning(err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Annotate(ctx.Err(), "retry is cancelled")
+		case <-timer.C:
+		}
+	}
+	return errors.Annotatef(err, "max attempts reached (%d)", p.config.MaxAttempts)
+}
+
+// exponential backoff with jitter, attempt starts from 1
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := time.Duration(p.config.DelayMs) * time.Millisecond
+	maxDelay := time.Duration(p.config.MaxDelayMs) * time.Millisecond
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	random := time.Duration(float64(delay) * p.config.Jitter * rand.Float64())
+	return delay - time.Duration(float64(delay)*p.config.Jitter) + random
+}
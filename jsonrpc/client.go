@@ -0,0 +1,230 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+/*
+Minimal JSON-RPC 2.0 client over HTTP.
+Providers use it instead of SDK clients when they need control over the transport.
+*/
+
+const defaultTimeout = 30 * time.Second
+
+type Request struct {
+	Id      uint64      `json:"id"`
+	Jsonrpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type Response struct {
+	Id      json.RawMessage `json:"id"`
+	Jsonrpc string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// error returned by node inside JSON-RPC response
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error, code=%d: %s", e.Code, e.Message)
+}
+
+// error returned when node responds with non-2xx HTTP status
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	//from Retry-After header, usually sent with 429 and 503
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("rpc http error, status code: %d", e.StatusCode)
+}
+
+type Client struct {
+	Url        string
+	httpClient *http.Client
+	lastId     uint64
+}
+
+func NewClient(url string, timeout time.Duration) *Client {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{
+		Url: url,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (c *Client) newRequest(method string, params []interface{}) *Request {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return &Request{
+		Id:      atomic.AddUint64(&c.lastId, 1),
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+}
+
+/*
+Call executes single JSON-RPC request and decodes response result into result argument.
+result may be nil, in that case result is not decoded.
+*/
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	var resp Response
+	err := c.post(ctx, c.newRequest(method, params), &resp)
+	if err != nil {
+		return errors.Annotatef(err, "can't call method=%s", method)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(resp.Result, result)
+	if err != nil {
+		return errors.Annotatef(err, "can't decode result, method=%s", method)
+	}
+	return nil
+}
+
+// single call of batch, Result and Error are set by Batch
+type BatchElem struct {
+	Method string
+	Params []interface{}
+	//pointer to value to decode result into, may be nil
+	Result interface{}
+	//error of this call, e.g. JSON-RPC error returned by node for this entry only
+	Error error
+}
+
+/*
+Batch executes calls in one HTTP request.
+Returned error means that the whole batch failed (transport, HTTP status, invalid response),
+failures of single calls are set to BatchElem.Error.
+*/
+func (c *Client) Batch(ctx context.Context, elems []*BatchElem) error {
+	if len(elems) == 0 {
+		return nil
+	}
+	requests := make([]*Request, len(elems))
+	byId := make(map[string]*BatchElem, len(elems))
+	for i, elem := range elems {
+		requests[i] = c.newRequest(elem.Method, elem.Params)
+		byId[strconv.FormatUint(requests[i].Id, 10)] = elem
+		elem.Error = nil
+	}
+
+	var responses []Response
+	err := c.post(ctx, requests, &responses)
+	if err != nil {
+		return errors.Annotatef(err, "can't call batch of %d requests", len(elems))
+	}
+
+	for _, resp := range responses {
+		elem, found := byId[strings.Trim(string(resp.Id), `"`)]
+		if !found {
+			continue
+		}
+		delete(byId, strings.Trim(string(resp.Id), `"`))
+		if resp.Error != nil {
+			elem.Error = resp.Error
+		} else if elem.Result != nil {
+			if err := json.Unmarshal(resp.Result, elem.Result); err != nil {
+				elem.Error = errors.Annotatef(err, "can't decode result, method=%s", elem.Method)
+			}
+		}
+	}
+	for _, elem := range byId {
+		elem.Error = errors.Errorf("no response in batch, method=%s", elem.Method)
+	}
+	return nil
+}
+
+// sends arbitrary payload, e.g. batch of requests, and returns response as is
+func (c *Client) Post(ctx context.Context, body interface{}) (json.RawMessage, error) {
+	var resp json.RawMessage
+	err := c.post(ctx, body, &resp)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't post request")
+	}
+	return resp, nil
+}
+
+func (c *Client) post(ctx context.Context, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errors.Annotate(err, "can't marshal request")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Annotate(err, "can't create request")
+	}
+	request.Header.Set("Content-Type", "application/json;charset=UTF-8")
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Annotate(err, "can't read response")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(data),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return errors.Annotate(err, "can't decode response")
+	}
+	return nil
+}
+
+// Retry-After is either number of seconds or HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,434 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"purrproof/smartcrawl/app"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+In-process job queue, there is no persistence: jobs which aren't processed are lost on exit.
+It's intended for local runs (crawl of block range, exec-* commands) and tests, when Redis isn't available.
+Jobs are marshaled on Add and restored by job handler, like jobs of asynq queue.
+*/
+
+var _ app.IJobQueue = (*JobQueue)(nil)
+
+// capacity of each queue channel, used if Queue.MemorySize isn't set
+const defaultQueueSize = 100000
+
+// used if MaxRetry isn't set in job config
+const defaultMaxRetry = 5
+
+// retry delay limit for default backoff
+const maxDefaultRetryDelay = time.Minute
+
+// how often idle workers check queues
+const pollInterval = 50 * time.Millisecond
+
+type task struct {
+	id       string
+	uniqueId string
+	queue    string
+	payload  []byte
+	retried  int
+}
+
+type queue struct {
+	tasks chan *task
+	//pending, active and waiting for retry tasks
+	unfinished int
+}
+
+type JobQueue struct {
+	WorkersNum uint
+	config     *app.QueueConfig
+	jobHandler func(ctx context.Context, payload []byte) ([]app.IJob, error)
+	//cancelled on Close, parent of job contexts
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	queues map[string]*queue
+	//unique ids of unfinished tasks by queue name
+	uniqueIds map[string]map[string]bool
+	lastId    uint64
+	failed    int
+}
+
+func NewJobQueue(conf *app.QueueConfig, jobHandler func(ctx context.Context, payload []byte) ([]app.IJob, error)) (*JobQueue, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	jobQueue := &JobQueue{
+		WorkersNum: uint(1),
+		config:     conf,
+		jobHandler: jobHandler,
+		ctx:        ctx,
+		cancel:     cancel,
+		queues:     make(map[string]*queue, 0),
+		uniqueIds:  make(map[string]map[string]bool, 0),
+	}
+	logrus.WithFields(logrus.Fields{}).Debug("memory job queue initialized")
+	return jobQueue, nil
+}
+
+// must be called with locked mutex
+func (q *JobQueue) getQueue(queueName string) *queue {
+	if qu, found := q.queues[queueName]; found {
+		return qu
+	}
+	size := q.config.MemorySize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	qu := &queue{
+		tasks: make(chan *task, size),
+	}
+	q.queues[queueName] = qu
+	q.uniqueIds[queueName] = make(map[string]bool, 0)
+	return qu
+}
+
+func (q *JobQueue) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
+	//queueName = params[0] if set
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't marshal job")
+	}
+
+	var queueName string
+	if len(params) == 0 {
+		//by default queue name is job name
+		queueName = job.GetDefaultQueueName()
+	} else {
+		queueName = params[0]
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	qu := q.getQueue(queueName)
+	uniqueId := job.GetUniqueId()
+	if uniqueId != "" && q.uniqueIds[queueName][uniqueId] {
+		return &app.JobInfo{
+			Id:        uniqueId,
+			Queue:     queueName,
+			Duplicate: true,
+		}, nil
+	}
+
+	q.lastId++
+	t := &task{
+		id:       strconv.FormatUint(q.lastId, 10),
+		uniqueId: uniqueId,
+		queue:    queueName,
+		payload:  payload,
+	}
+	if uniqueId != "" {
+		t.id = uniqueId
+	}
+
+	//channel isn't read under mutex, so add mustn't block: worker adds child jobs itself
+	select {
+	case qu.tasks <- t:
+	default:
+		return nil, errors.Errorf("queue is full, queue=%s, size=%d", queueName, cap(qu.tasks))
+	}
+	qu.unfinished++
+	if uniqueId != "" {
+		q.uniqueIds[queueName][uniqueId] = true
+	}
+
+	return &app.JobInfo{
+		Id:    t.id,
+		Queue: queueName,
+	}, nil
+}
+
+func (q *JobQueue) GetQueueDepth(queueName string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if qu, found := q.queues[queueName]; found {
+		return qu.unfinished, nil
+	}
+	return 0, nil
+}
+
+// stops workers, Process returns, jobs in progress get cancelled context
+func (q *JobQueue) Close() error {
+	q.cancel()
+	logrus.Info("memory queue closed")
+	return nil
+}
+
+/*
+Process runs workers for comma-separated queues until all these queues are empty
+(including jobs waiting for retry and child jobs added by handled jobs) or until queue is closed.
+"*" at the end of name matches any suffix, e.g. "job:property:set:*" for queues of all properties.
+Unlike asynq, Process doesn't wait for new jobs after queues become empty.
+Returns error if some jobs failed after all retries.
+*/
+func (q *JobQueue) Process(qname string, workersNum uint) error {
+	if workersNum == 0 {
+		workersNum = 1
+	}
+	q.WorkersNum = workersNum
+
+	patterns := make([]string, 0)
+	for _, name := range strings.Split(qname, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			patterns = append(patterns, name)
+		}
+	}
+	if len(patterns) == 0 {
+		return errors.New("queue name is not defined")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"queue_name": qname,
+		"workers":    workersNum,
+	}).Info("start processing")
+
+	q.mu.Lock()
+	failedBefore := q.failed
+	q.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := uint(0); i < workersNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(patterns)
+		}()
+	}
+	wg.Wait()
+
+	q.mu.Lock()
+	failed := q.failed - failedBefore
+	//child jobs may be added to queues which aren't processed
+	for name, qu := range q.queues {
+		if !matchQueue(patterns, name) && qu.unfinished > 0 {
+			logrus.WithFields(logrus.Fields{
+				"queue": name,
+				"depth": qu.unfinished,
+			}).Warning("queue isn't processed, jobs are left")
+		}
+	}
+	q.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"queue_name": qname,
+		"failed":     failed,
+	}).Info("stop processing")
+
+	if q.ctx.Err() != nil {
+		return nil
+	} else if failed > 0 {
+		return errors.Errorf("%d job(s) failed", failed)
+	}
+	return nil
+}
+
+func (q *JobQueue) work(patterns []string) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if q.ctx.Err() != nil {
+			return
+		}
+		t := q.next(patterns)
+		if t != nil {
+			q.handle(t)
+			continue
+		}
+		if q.isDrained(patterns) {
+			return
+		}
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// takes task from non-empty queue, queues with greater priority are chosen more often
+func (q *JobQueue) next(patterns []string) *task {
+	q.mu.Lock()
+	candidates := make([]chan *task, 0)
+	cweights := make([]int, 0)
+	total := 0
+	for name, qu := range q.queues {
+		if len(qu.tasks) == 0 || !matchQueue(patterns, name) {
+			continue
+		}
+		weight := q.config.GetJobConfig(name).Priority
+		if weight <= 0 {
+			weight = 1
+		}
+		candidates = append(candidates, qu.tasks)
+		cweights = append(cweights, weight)
+		total += weight
+	}
+	q.mu.Unlock()
+
+	for len(candidates) > 0 {
+		pick := rand.Intn(total)
+		i := 0
+		for ; pick >= cweights[i]; i++ {
+			pick -= cweights[i]
+		}
+		select {
+		case t := <-candidates[i]:
+			return t
+		default:
+			//taken by other worker
+			total -= cweights[i]
+			candidates = append(candidates[:i], candidates[i+1:]...)
+			cweights = append(cweights[:i], cweights[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (q *JobQueue) isDrained(patterns []string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for name, qu := range q.queues {
+		if qu.unfinished > 0 && matchQueue(patterns, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchQueue(patterns []string, queueName string) bool {
+	for _, pattern := range patterns {
+		if pattern == queueName {
+			return true
+		} else if strings.HasSuffix(pattern, "*") && strings.HasPrefix(queueName, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *JobQueue) handle(t *task) {
+	jconf := q.config.GetJobConfig(t.queue)
+	maxRetry, defined := jconf.GetMaxRetry()
+	if !defined {
+		maxRetry = defaultMaxRetry
+	}
+	ctx := app.WithJobAttempt(q.ctx, t.retried, maxRetry)
+	if jconf.TimeoutSec > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(jconf.TimeoutSec)*time.Second)
+		defer cancel()
+	}
+
+	newJobs, err := q.jobHandler(ctx, t.payload)
+	//child jobs are added like in asynq queue handler
+	for _, job := range newJobs {
+		info, aerr := q.Add(job)
+		if aerr != nil {
+			err = errors.Annotate(aerr, "can't add job to queue")
+			break
+		}
+		logrus.WithFields(logrus.Fields{
+			"id":        info.Id,
+			"queue":     info.Queue,
+			"duplicate": info.Duplicate,
+		}).Info("job queued")
+	}
+
+	fields := logrus.Fields{
+		"id":      t.id,
+		"queue":   t.queue,
+		"retried": t.retried,
+	}
+	if err == nil {
+		q.finish(t, false)
+		return
+	} else if q.ctx.Err() != nil {
+		//queue is closed, job is lost as any other job in memory
+		q.finish(t, false)
+		return
+	}
+
+	if app.IsPermanentError(err) {
+		logrus.WithFields(fields).WithError(err).Error("job failed, error is permanent")
+		q.finish(t, true)
+		return
+	} else if t.retried >= maxRetry {
+		logrus.WithFields(fields).WithError(err).Error("job failed, max retry reached")
+		q.finish(t, true)
+		return
+	}
+
+	delay, defined := jconf.RetryDelay(t.retried)
+	if !defined {
+		delay = defaultRetryDelay(t.retried)
+	}
+	t.retried++
+	fields["delay"] = delay
+	logrus.WithFields(fields).WithError(err).Warning("job failed, will be retried")
+
+	time.AfterFunc(delay, func() {
+		q.retry(t)
+	})
+}
+
+// puts task back into its queue, task is still unfinished
+func (q *JobQueue) retry(t *task) {
+	if q.ctx.Err() != nil {
+		q.finish(t, false)
+		return
+	}
+	q.mu.Lock()
+	qu := q.getQueue(t.queue)
+	select {
+	case qu.tasks <- t:
+		q.mu.Unlock()
+	default:
+		q.mu.Unlock()
+		logrus.WithFields(logrus.Fields{
+			"id":    t.id,
+			"queue": t.queue,
+		}).Error("queue is full, job is dropped")
+		q.finish(t, true)
+	}
+}
+
+func (q *JobQueue) finish(t *task, failed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	qu := q.getQueue(t.queue)
+	qu.unfinished--
+	if t.uniqueId != "" {
+		delete(q.uniqueIds[t.queue], t.uniqueId)
+	}
+	if failed {
+		q.failed++
+	}
+}
+
+// 1s, 2s, 4s... but not more than maxDefaultRetryDelay
+func defaultRetryDelay(retried int) time.Duration {
+	delay := time.Second
+	for i := 0; i < retried; i++ {
+		delay *= 2
+		if delay >= maxDefaultRetryDelay {
+			return maxDefaultRetryDelay
+		}
+	}
+	return delay
+}
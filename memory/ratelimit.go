@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"golang.org/x/time/rate"
+)
+
+var _ app.IRateLimiter = (*RateLimiter)(nil)
+
+// token bucket of the process, for processes without Redis of job queue
+type RateLimiter struct {
+	limiter *rate.Limiter
+}
+
+func NewRateLimiter(conf *app.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(conf.RequestsPerSec), conf.Burst),
+	}
+}
+
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	err := l.limiter.Wait(ctx)
+	if err != nil {
+		return app.NewClassifiedError(app.ErrorKindTransient, errors.Annotate(err, "rate limit wait is interrupted"))
+	}
+	return nil
+}
+
+func (l *RateLimiter) Close() error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package mocks
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.ICallRepository = (*CallRepositoryMock)(nil)
+
+type CallRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *CallRepositoryMock) SaveCalls(ctx context.Context, calls []*app.ItemCall) error {
+	args := m.Called(calls)
+	return args.Error(0)
+}
+
+func (m *CallRepositoryMock) UpdateCallStats(ctx context.Context, ids []*app.ItemId) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+
+func (m *CallRepositoryMock) Close() error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.IEventRepository = (*EventRepositoryMock)(nil)
+
+type EventRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *EventRepositoryMock) SaveEvents(ctx context.Context, events []*app.ItemEvent) error {
+	args := m.Called(events)
+	return args.Error(0)
+}
+
+func (m *EventRepositoryMock) Close() error {
+	return nil
+}
@@ -1,7 +1,9 @@
 package mocks
 
 import (
+	"context"
 	"purrproof/smartcrawl/app"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -12,20 +14,28 @@ type ItemRepositoryMock struct {
 	mock.Mock
 }
 
-func (m *ItemRepositoryMock) Get(item app.IItem) (app.IItem, error) {
+func (m *ItemRepositoryMock) Get(ctx context.Context, item app.IItem) (app.IItem, error) {
 	args := m.Called(item)
 	return args.Get(0).(app.IItem), args.Error(1)
 }
 
-func (m *ItemRepositoryMock) GetAllWithoutProperty(provider app.IItemProvider, propName string, limit uint) ([]app.IItem, error) {
+func (m *ItemRepositoryMock) GetAllWithoutProperty(ctx context.Context, provider app.IItemProvider, propName string, limit uint) ([]app.IItem, error) {
 	return nil, nil
 }
 
-func (m *ItemRepositoryMock) Save(item app.IItem) error {
+func (m *ItemRepositoryMock) GetAllWithStaleProperty(ctx context.Context, provider app.IItemProvider, propName string, before time.Time, limit uint) ([]app.IItem, error) {
+	return nil, nil
+}
+
+func (m *ItemRepositoryMock) GetGroups(ctx context.Context, provider app.IItemProvider, groupBy string, fields []string, minCount uint, limit uint) ([]*app.ItemGroup, error) {
+	return nil, nil
+}
+
+func (m *ItemRepositoryMock) Save(ctx context.Context, item app.IItem) error {
 	return nil
 }
 
-func (m *ItemRepositoryMock) Update(item app.IItem, fieldNames []string) error {
+func (m *ItemRepositoryMock) Update(ctx context.Context, item app.IItem, fieldNames []string) error {
 	args := m.Called(item, fieldNames)
 	return args.Error(0)
 }
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.IContainerLedger = (*ContainerLedgerMock)(nil)
+
+type ContainerLedgerMock struct {
+	mock.Mock
+}
+
+func (m *ContainerLedgerMock) MarkQueued(ctx context.Context, provKey string, container *app.ItemsContainer, queuedAt time.Time) error {
+	args := m.Called(provKey, container, queuedAt)
+	return args.Error(0)
+}
+
+func (m *ContainerLedgerMock) MarkProcessing(ctx context.Context, provKey string, container *app.ItemsContainer) error {
+	args := m.Called(provKey, container)
+	return args.Error(0)
+}
+
+func (m *ContainerLedgerMock) MarkDone(ctx context.Context, provKey string, container *app.ItemsContainer, itemsCount int) error {
+	args := m.Called(provKey, container, itemsCount)
+	return args.Error(0)
+}
+
+func (m *ContainerLedgerMock) MarkFailed(ctx context.Context, provKey string, container *app.ItemsContainer, reason error) error {
+	args := m.Called(provKey, container, reason)
+	return args.Error(0)
+}
+
+func (m *ContainerLedgerMock) Walk(ctx context.Context, provKey string, from, to uint, fn func(record *app.ContainerRecord) error) error {
+	return nil
+}
+
+func (m *ContainerLedgerMock) Close() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.IItemProvider = (*ItemProviderMock)(nil)
+var _ app.IActivityProvider = (*ItemProviderMock)(nil)
+var _ app.IEventProvider = (*ItemProviderMock)(nil)
+
+/*
+NewItem creates plain app.Item, so mock is usable with any item type.
+Items returned from FetchContainerItems are defined by test.
+*/
+type ItemProviderMock struct {
+	mock.Mock
+}
+
+func (m *ItemProviderMock) NewItem(id string) app.IItem {
+	return app.NewItem("Mock", "1", id)
+}
+
+func (m *ItemProviderMock) GetContainersList(ctx context.Context, number uint, startAfter *app.ItemsContainer) ([]*app.ItemsContainer, error) {
+	args := m.Called(number, startAfter)
+	return args.Get(0).([]*app.ItemsContainer), args.Error(1)
+}
+
+func (m *ItemProviderMock) FetchContainerItems(ctx context.Context, container *app.ItemsContainer) ([]app.IItem, error) {
+	args := m.Called(container)
+	items, _ := args.Get(0).([]app.IItem)
+	return items, args.Error(1)
+}
+
+func (m *ItemProviderMock) FetchContainerCalls(ctx context.Context, container *app.ItemsContainer) ([]*app.ItemCall, error) {
+	args := m.Called(container)
+	calls, _ := args.Get(0).([]*app.ItemCall)
+	return calls, args.Error(1)
+}
+
+func (m *ItemProviderMock) FetchContainerEvents(ctx context.Context, container *app.ItemsContainer) ([]*app.ItemEvent, error) {
+	args := m.Called(container)
+	events, _ := args.Get(0).([]*app.ItemEvent)
+	return events, args.Error(1)
+}
+
+func (m *ItemProviderMock) PrepareItemsArray(limit uint) []app.IItem {
+	return nil
+}
+
+func (m *ItemProviderMock) Close() error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.IJobQueue = (*JobQueueMock)(nil)
+
+type JobQueueMock struct {
+	mock.Mock
+}
+
+func (m *JobQueueMock) Add(job app.IJob, params ...string) (*app.JobInfo, error) {
+	args := m.Called(job)
+	info, _ := args.Get(0).(*app.JobInfo)
+	return info, args.Error(1)
+}
+
+func (m *JobQueueMock) GetQueueDepth(queue string) (int, error) {
+	args := m.Called(queue)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *JobQueueMock) Close() error {
+	return nil
+}
+
+func (m *JobQueueMock) Process(queue string, workersNum uint) error {
+	return nil
+}
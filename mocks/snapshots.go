@@ -0,0 +1,28 @@
+package mocks
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.ISnapshotStore = (*SnapshotStoreMock)(nil)
+
+type SnapshotStoreMock struct {
+	mock.Mock
+}
+
+func (m *SnapshotStoreMock) SaveSnapshot(ctx context.Context, snapshot *app.PropertySnapshot) error {
+	args := m.Called(snapshot)
+	return args.Error(0)
+}
+
+func (m *SnapshotStoreMock) PruneSnapshots(ctx context.Context, id *app.ItemId, property string, keep int) error {
+	args := m.Called(id, property, keep)
+	return args.Error(0)
+}
+
+func (m *SnapshotStoreMock) Close() error {
+	return nil
+}
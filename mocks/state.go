@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"purrproof/smartcrawl/app"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ app.IAppStateStore = (*AppStateStoreMock)(nil)
+
+type AppStateStoreMock struct {
+	mock.Mock
+}
+
+func (m *AppStateStoreMock) Get(provKey string, cursor ...string) (*app.AppState, error) {
+	args := m.Called(provKey, cursor)
+	state, _ := args.Get(0).(*app.AppState)
+	return state, args.Error(1)
+}
+
+func (m *AppStateStoreMock) Save(provKey string, state *app.AppState) error {
+	args := m.Called(provKey, state)
+	return args.Error(0)
+}
+
+func (m *AppStateStoreMock) GetAll(provKey string) ([]*app.AppState, error) {
+	args := m.Called(provKey)
+	states, _ := args.Get(0).([]*app.AppState)
+	return states, args.Error(1)
+}
+
+func (m *AppStateStoreMock) Reset(provKey string, cursor ...string) error {
+	args := m.Called(provKey, cursor)
+	return args.Error(0)
+}
+
+func (m *AppStateStoreMock) Migrate(provKey string) (bool, error) {
+	args := m.Called(provKey)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *AppStateStoreMock) Close() error {
+	return nil
+}
@@ -0,0 +1,406 @@
+package mongo
+
+import (
+	"context"
+
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ app.ICallRepository = (*CallRepository)(nil)
+
+/*
+CallRepository keeps calls in "call" collection, first calls of callers in "caller" collection
+and CallStats in "callstats" collection, stats are copied into "calls" field of items.
+Stats are incremented by calls and callers which are new, so cost doesn't depend on history of item.
+New calls and callers are pending until they are counted: retried job counts them if stats update failed.
+*/
+type CallRepository struct {
+	client     *mongo.Client
+	config     *app.StorageConfig
+	callColl   *mongo.Collection
+	callerColl *mongo.Collection
+	statsColl  *mongo.Collection
+	itemColl   *mongo.Collection
+}
+
+const callCollName = "call"
+const callerCollName = "caller"
+const callStatsCollName = "callstats"
+
+// item field with app.CallStats
+const callStatsField = "calls"
+
+const duplicateKeyCode = 11000
+
+// call which isn't counted in stats yet
+type pendingCall struct {
+	*app.ItemCall `bson:",inline"`
+	Pending       bool `bson:"pending"`
+}
+
+// the first call of sender to item, it's counted by container job of that call (txid)
+type caller struct {
+	app.ItemId `bson:",inline"`
+	Sender     string `bson:"sender"`
+	Txid       string `bson:"txid"`
+	Pending    bool   `bson:"pending"`
+}
+
+func NewCallRepository(conf *app.StorageConfig) (*CallRepository, error) {
+	clientOptions := options.Client().ApplyURI(conf.Uri)
+
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	// check connection
+	err = client.Ping(context.TODO(), nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	db := client.Database(conf.DbName)
+	callColl := db.Collection(callCollName)
+	_, err = callColl.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "txid", Value: 1}, {Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}, {Key: "seq", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create call indexes")
+	}
+	callerColl := db.Collection(callerCollName)
+	_, err = callerColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}, {Key: "sender", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create caller indexes")
+	}
+	statsColl := db.Collection(callStatsCollName)
+	_, err = statsColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create call stats indexes")
+	}
+
+	logrus.WithFields(logrus.Fields{}).Debug("call repository initialized")
+
+	return &CallRepository{
+		client:     client,
+		config:     conf,
+		callColl:   callColl,
+		callerColl: callerColl,
+		statsColl:  statsColl,
+		itemColl:   db.Collection(itemCollName),
+	}, nil
+}
+
+/*
+Calls are stored as pending, then pending calls of the batch and callers they brought are counted in stats.
+Calls of container are saved by one job at a time, so its pending calls and callers aren't counted by other jobs.
+Stats are counted twice only if process dies between stats update and clearing of pending flags.
+*/
+func (r *CallRepository) SaveCalls(ctx context.Context, calls []*app.ItemCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(calls))
+	ids := make([]*app.ItemId, 0)
+	seen := make(map[app.ItemId]bool)
+	for i, call := range calls {
+		filter := bson.M{"provname": call.Item.ProvName, "provbranch": call.Item.ProvBranch, "id": call.Item.Id, "txid": call.Txid}
+		update := bson.M{"$setOnInsert": &pendingCall{ItemCall: call, Pending: true}}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+		if !seen[*call.Item] {
+			seen[*call.Item] = true
+			ids = append(ids, call.Item)
+		}
+	}
+	inserted, err := upsertMany(ctx, r.callColl, models)
+	if err != nil {
+		return errors.Annotate(err, "can't save calls")
+	}
+
+	pending, err := r.findPendingCalls(ctx, calls)
+	if err != nil {
+		return errors.Annotate(err, "can't find pending calls")
+	}
+	stats := make(map[app.ItemId]*app.CallStats)
+	getStats := func(id app.ItemId) *app.CallStats {
+		if _, found := stats[id]; !found {
+			stats[id] = &app.CallStats{}
+		}
+		return stats[id]
+	}
+	for _, call := range pending {
+		itemStats := getStats(*call.Item)
+		itemStats.Total++
+		if !call.Success {
+			itemStats.Failed++
+		}
+		if call.Seq > itemStats.LastSeq {
+			itemStats.LastSeq = call.Seq
+		}
+	}
+
+	callers, err := r.saveCallers(ctx, pending)
+	if err != nil {
+		return errors.Annotate(err, "can't save callers")
+	}
+	for _, pendingCaller := range callers {
+		getStats(pendingCaller.ItemId).Callers++
+	}
+
+	err = r.incStats(ctx, stats)
+	if err != nil {
+		return errors.Annotate(err, "can't update call stats")
+	}
+	err = r.clearPending(ctx, pending, callers)
+	if err != nil {
+		return errors.Annotate(err, "can't clear pending calls")
+	}
+	logrus.WithFields(logrus.Fields{
+		"calls":       len(calls),
+		"new_calls":   len(inserted),
+		"counted":     len(pending),
+		"new_callers": len(callers),
+		"items":       len(ids),
+	}).Debug("calls saved")
+
+	return errors.Trace(r.UpdateCallStats(ctx, ids))
+}
+
+// pending calls with txids of batch, they are inserted by this or by failed attempt of the job
+func (r *CallRepository) findPendingCalls(ctx context.Context, calls []*app.ItemCall) ([]*app.ItemCall, error) {
+	txids := make([]string, 0, len(calls))
+	for _, call := range calls {
+		txids = append(txids, call.Txid)
+	}
+	filter := bson.M{
+		"provname":   calls[0].Item.ProvName,
+		"provbranch": calls[0].Item.ProvBranch,
+		"txid":       bson.M{"$in": txids},
+		"pending":    true,
+	}
+	cursor, err := r.callColl.Find(ctx, filter)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer cursor.Close(ctx)
+	result := make([]*app.ItemCall, 0)
+	for cursor.Next(ctx) {
+		call := &app.ItemCall{}
+		if err := cursor.Decode(call); err != nil {
+			return nil, errors.Trace(err)
+		}
+		result = append(result, call)
+	}
+	return result, errors.Trace(cursor.Err())
+}
+
+/*
+Stores senders of calls which are new callers of items and returns pending callers brought by these calls.
+Caller is owned by txid of its first stored call, so concurrent jobs don't count the same caller.
+*/
+func (r *CallRepository) saveCallers(ctx context.Context, calls []*app.ItemCall) ([]*caller, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	models := make([]mongo.WriteModel, 0, len(calls))
+	txids := make([]string, 0, len(calls))
+	itemIds := make([]string, 0, len(calls))
+	for _, call := range calls {
+		filter := bson.M{"provname": call.Item.ProvName, "provbranch": call.Item.ProvBranch, "id": call.Item.Id, "sender": call.Sender}
+		update := bson.M{"$setOnInsert": &caller{ItemId: *call.Item, Sender: call.Sender, Txid: call.Txid, Pending: true}}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		txids = append(txids, call.Txid)
+		itemIds = append(itemIds, call.Item.Id)
+	}
+	_, err := upsertMany(ctx, r.callerColl, models)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	filter := bson.M{
+		"provname":   calls[0].Item.ProvName,
+		"provbranch": calls[0].Item.ProvBranch,
+		"id":         bson.M{"$in": itemIds},
+		"txid":       bson.M{"$in": txids},
+		"pending":    true,
+	}
+	cursor, err := r.callerColl.Find(ctx, filter)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer cursor.Close(ctx)
+	result := make([]*caller, 0)
+	for cursor.Next(ctx) {
+		found := &caller{}
+		if err := cursor.Decode(found); err != nil {
+			return nil, errors.Trace(err)
+		}
+		result = append(result, found)
+	}
+	return result, errors.Trace(cursor.Err())
+}
+
+/*
+Stats document is upserted, so calls of items which aren't crawled yet are counted too.
+Concurrent upsert of the same document fails with duplicate key error, such updates are repeated once.
+*/
+func (r *CallRepository) incStats(ctx context.Context, stats map[app.ItemId]*app.CallStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(stats))
+	for id, itemStats := range stats {
+		filter := bson.M{"provname": id.ProvName, "provbranch": id.ProvBranch, "id": id.Id}
+		update := bson.M{
+			"$inc": bson.M{"total": itemStats.Total, "failed": itemStats.Failed, "callers": itemStats.Callers},
+			"$max": bson.M{"lastseq": itemStats.LastSeq},
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+	_, err := r.statsColl.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return errors.Trace(err)
+	}
+	repeat := make([]mongo.WriteModel, 0, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return errors.Trace(err)
+		}
+		repeat = append(repeat, models[writeErr.Index])
+	}
+	_, err = r.statsColl.BulkWrite(ctx, repeat, options.BulkWrite().SetOrdered(false))
+	return errors.Trace(err)
+}
+
+func (r *CallRepository) clearPending(ctx context.Context, calls []*app.ItemCall, callers []*caller) error {
+	if len(calls) > 0 {
+		models := make([]mongo.WriteModel, 0, len(calls))
+		for _, call := range calls {
+			filter := bson.M{"provname": call.Item.ProvName, "provbranch": call.Item.ProvBranch, "id": call.Item.Id, "txid": call.Txid}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": bson.M{"pending": false}}))
+		}
+		_, err := r.callColl.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(callers) > 0 {
+		models := make([]mongo.WriteModel, 0, len(callers))
+		for _, pendingCaller := range callers {
+			filter := bson.M{"provname": pendingCaller.ProvName, "provbranch": pendingCaller.ProvBranch, "id": pendingCaller.Id, "sender": pendingCaller.Sender}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": bson.M{"pending": false}}))
+		}
+		_, err := r.callerColl.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+/*
+Copies stats of items from "callstats" collection to items which exist.
+Item saved later gets its stats by the next call of UpdateCallStats (see job:container:process).
+Stats only grow, so they are set by $max: stale copy of concurrent update doesn't overwrite newer one.
+*/
+func (r *CallRepository) UpdateCallStats(ctx context.Context, ids []*app.ItemId) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	or := make(bson.A, 0, len(ids))
+	for _, id := range ids {
+		or = append(or, bson.M{"provname": id.ProvName, "provbranch": id.ProvBranch, "id": id.Id})
+	}
+	cursor, err := r.statsColl.Find(ctx, bson.M{"$or": or})
+	if err != nil {
+		return errors.Annotate(err, "can't get call stats")
+	}
+	defer cursor.Close(ctx)
+
+	models := make([]mongo.WriteModel, 0, len(ids))
+	for cursor.Next(ctx) {
+		var doc struct {
+			app.ItemId `bson:",inline"`
+			Stats      app.CallStats `bson:",inline"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return errors.Trace(err)
+		}
+		filter := bson.M{"provname": doc.ProvName, "provbranch": doc.ProvBranch, "id": doc.Id}
+		update := bson.M{"$max": bson.M{
+			callStatsField + ".total":   doc.Stats.Total,
+			callStatsField + ".failed":  doc.Stats.Failed,
+			callStatsField + ".callers": doc.Stats.Callers,
+			callStatsField + ".lastseq": doc.Stats.LastSeq,
+		}}
+		//no upsert: items which aren't crawled yet aren't created
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+	}
+	if err := cursor.Err(); err != nil {
+		return errors.Trace(err)
+	} else if len(models) == 0 {
+		return nil
+	}
+	_, err = r.itemColl.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return errors.Annotate(err, "can't update call stats of items")
+	}
+	return nil
+}
+
+/*
+Executes upserts and returns indexes of models which inserted documents.
+Concurrent upsert of the same document fails with duplicate key error, it's the same as found document.
+*/
+func upsertMany(ctx context.Context, coll *mongo.Collection, models []mongo.WriteModel) (map[int]bool, error) {
+	inserted := make(map[int]bool)
+	result, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if writeErr.Code != duplicateKeyCode {
+				return nil, errors.Trace(err)
+			}
+		}
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if result != nil {
+		for index := range result.UpsertedIDs {
+			inserted[int(index)] = true
+		}
+	}
+	return inserted, nil
+}
+
+func (r *CallRepository) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Disconnect(context.TODO())
+	if err != nil {
+		return errors.Annotate(err, "can't disconnect mongo client")
+	}
+	logrus.Info("call repository closed")
+	return nil
+}
@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"context"
+
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ app.IEventRepository = (*EventRepository)(nil)
+
+// EventRepository keeps events in "event" collection, one document per event of transaction
+type EventRepository struct {
+	client *mongo.Client
+	config *app.StorageConfig
+	coll   *mongo.Collection
+}
+
+const eventCollName = "event"
+
+func NewEventRepository(conf *app.StorageConfig) (*EventRepository, error) {
+	clientOptions := options.Client().ApplyURI(conf.Uri)
+
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	// check connection
+	err = client.Ping(context.TODO(), nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	coll := client.Database(conf.DbName).Collection(eventCollName)
+	_, err = coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "txid", Value: 1}, {Key: "index", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			//events of contract by name in order of blocks, e.g. transfers of token
+			Keys: bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}, {Key: "name", Value: 1}, {Key: "seq", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "name", Value: 1}, {Key: "seq", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create event indexes")
+	}
+
+	logrus.WithFields(logrus.Fields{}).Debug("event repository initialized")
+
+	return &EventRepository{
+		client: client,
+		config: conf,
+		coll:   coll,
+	}, nil
+}
+
+func (r *EventRepository) SaveEvents(ctx context.Context, events []*app.ItemEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(events))
+	for i, event := range events {
+		filter := bson.M{"provname": event.Item.ProvName, "provbranch": event.Item.ProvBranch, "txid": event.Txid, "index": event.Index}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$setOnInsert": event}).SetUpsert(true)
+	}
+	inserted, err := upsertMany(ctx, r.coll, models)
+	if err != nil {
+		return errors.Annotate(err, "can't save events")
+	}
+	logrus.WithFields(logrus.Fields{
+		"events":     len(events),
+		"new_events": len(inserted),
+	}).Debug("events saved")
+	return nil
+}
+
+func (r *EventRepository) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Disconnect(context.TODO())
+	if err != nil {
+		return errors.Annotate(err, "can't disconnect mongo client")
+	}
+	logrus.Info("event repository closed")
+	return nil
+}
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"purrproof/smartcrawl/app"
@@ -21,10 +22,13 @@ type ItemRepository struct {
 	config   *app.StorageConfig
 	collName string
 	coll     *mongo.Collection
+	//collections of item kinds other than app.DefaultItemKind, they are created on first use
+	kindColls map[string]*mongo.Collection
+	mu        sync.Mutex
 }
 
-//it's possible to move this into config.Storage, but I don't want to make config too big
-//it could be done later in case of need
+// it's possible to move this into config.Storage, but I don't want to make config too big
+// it could be done later in case of need
 const itemCollName = "item"
 
 func NewItemRepository(conf *app.StorageConfig) (*ItemRepository, error) {
@@ -44,16 +48,58 @@ func NewItemRepository(conf *app.StorageConfig) (*ItemRepository, error) {
 	logrus.WithFields(logrus.Fields{}).Debug("item repository initialized")
 
 	coll := client.Database(conf.DbName).Collection(itemCollName)
+	_, err = coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			//clones search, see GetGroups
+			Keys: bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "codehash", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create item indexes")
+	}
 
 	return &ItemRepository{
-		client:   client,
-		config:   conf,
-		collName: itemCollName,
-		coll:     coll,
+		client:    client,
+		config:    conf,
+		collName:  itemCollName,
+		coll:      coll,
+		kindColls: make(map[string]*mongo.Collection),
 	}, nil
 }
 
-func (s *ItemRepository) Save(item app.IItem) error {
+// items of app.DefaultItemKind are in "item" collection, other kinds in "item_<kind>"
+func getItemCollName(kind string) string {
+	if kind == app.DefaultItemKind {
+		return itemCollName
+	}
+	return itemCollName + "_" + kind
+}
+
+func (s *ItemRepository) collection(ctx context.Context, item app.IItem) (*mongo.Collection, error) {
+	kind := app.GetItemKind(item)
+	if kind == app.DefaultItemKind {
+		return s.coll, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if coll, found := s.kindColls[kind]; found {
+		return coll, nil
+	}
+	coll := s.client.Database(s.config.DbName).Collection(getItemCollName(kind))
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}},
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't create indexes of item kind=%s", kind)
+	}
+	s.kindColls[kind] = coll
+	return coll, nil
+}
+
+func (s *ItemRepository) Save(ctx context.Context, item app.IItem) error {
+	if err := app.NormalizeItemId(item); err != nil {
+		return errors.Annotate(err, "invalid item id")
+	}
 	item.SetBaseField("UpdatedAt", time.Now())
 	filterId := item.GetId()
 	filter, err := bson.Marshal(filterId)
@@ -61,16 +107,24 @@ func (s *ItemRepository) Save(item app.IItem) error {
 		return errors.Annotate(err, "can't marshal item filter")
 	}
 
-	update := bson.D{{"$set", item}}
+	coll, err := s.collection(ctx, item)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	update := bson.D{{Key: "$set", Value: item}}
 	opts := options.Update().SetUpsert(true)
-	_, err = s.coll.UpdateOne(context.TODO(), filter, update, opts)
+	_, err = coll.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
 		return errors.Annotate(err, "can't save item")
 	}
 	return nil
 }
 
-func (s *ItemRepository) Update(item app.IItem, fieldNames []string) error {
+func (s *ItemRepository) Update(ctx context.Context, item app.IItem, fieldNames []string) error {
+	if err := app.NormalizeItemId(item); err != nil {
+		return errors.Annotate(err, "invalid item id")
+	}
 	item.SetBaseField("UpdatedAt", time.Now())
 	filterId := item.GetId()
 	filter, err := bson.Marshal(filterId)
@@ -95,22 +149,34 @@ func (s *ItemRepository) Update(item app.IItem, fieldNames []string) error {
 		"$set": fields,
 	}
 
-	_, err = s.coll.UpdateOne(context.TODO(), filter, update)
+	coll, err := s.collection(ctx, item)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = coll.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return errors.Annotate(err, "can't update item")
 	}
 	return nil
 }
 
-func (s *ItemRepository) Get(item app.IItem) (app.IItem, error) {
+// id of item may be in any form, e.g. contract address in hex or bech32
+func (s *ItemRepository) Get(ctx context.Context, item app.IItem) (app.IItem, error) {
+	if err := app.NormalizeItemId(item); err != nil {
+		return nil, errors.Annotate(err, "invalid item id")
+	}
 	filterId := item.GetId()
 	filter, err := bson.Marshal(filterId)
 	if err != nil {
 		return nil, errors.Annotate(err, "can't marshal item filter")
 	}
 
+	coll, err := s.collection(ctx, item)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	//if .Decode(&item), there is error "no decoder found for app.IItem"
-	err = s.coll.FindOne(context.TODO(), filter).Decode(item)
+	err = coll.FindOne(ctx, filter).Decode(item)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			logrus.WithFields(logrus.Fields{
@@ -123,20 +189,36 @@ func (s *ItemRepository) Get(item app.IItem) (app.IItem, error) {
 	return item, nil
 }
 
-func (s *ItemRepository) GetAllWithoutProperty(provider app.IItemProvider, propName string, limit uint) ([]app.IItem, error) {
+func (s *ItemRepository) GetAllWithoutProperty(ctx context.Context, provider app.IItemProvider, propName string, limit uint) ([]app.IItem, error) {
+	return s.findByProperty(ctx, provider, propName, "", bson.M{"$exists": false}, limit)
+}
+
+func (s *ItemRepository) GetAllWithStaleProperty(ctx context.Context, provider app.IItemProvider, propName string, before time.Time, limit uint) ([]app.IItem, error) {
+	return s.findByProperty(ctx, provider, propName, app.SnapshotTimeField, bson.M{"$lt": before}, limit)
+}
+
+// condition is applied to property field or to its subfield if it isn't empty
+func (s *ItemRepository) findByProperty(ctx context.Context, provider app.IItemProvider, propName string, subfield string, condition bson.M, limit uint) ([]app.IItem, error) {
 	testItem := provider.NewItem("")
 	dbField, err := reflections.GetFieldTag(testItem, propName, "bson")
 	if err != nil {
 		return nil, errors.Annotatef(err, "can't get item field, fname=%s", propName)
 	}
+	if subfield != "" {
+		dbField += "." + subfield
+	}
 
 	filter := bson.M(testItem.GetProviderFilter())
-	filter[dbField] = bson.M{"$exists": false}
+	filter[dbField] = condition
 
 	findOptions := options.Find()
 	findOptions.SetLimit(int64(limit))
 
-	cursor, err := s.coll.Find(context.TODO(), filter, findOptions)
+	coll, err := s.collection(ctx, testItem)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cursor, err := coll.Find(ctx, filter, findOptions)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			logrus.WithFields(logrus.Fields{
@@ -148,7 +230,8 @@ func (s *ItemRepository) GetAllWithoutProperty(provider app.IItemProvider, propN
 	}
 
 	//https://kb.objectrocket.com/mongo-db/how-to-get-mongodb-documents-using-golang-446#use+golang%5C%27s+context+package+to+manage+the+mongodb+api+requests
-	ctx, _ := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
 	result := make([]app.IItem, 0)
 	for cursor.Next(ctx) {
 		item := provider.NewItem("")
@@ -162,6 +245,76 @@ func (s *ItemRepository) GetAllWithoutProperty(provider app.IItemProvider, propN
 	return result, nil
 }
 
+/*
+GetGroups aggregates items by value of groupBy property, groups smaller than minCount are skipped.
+All items of group are in one document, so it must fit into 16MB (~100k items with a few small fields).
+*/
+func (s *ItemRepository) GetGroups(ctx context.Context, provider app.IItemProvider, groupBy string, fields []string, minCount uint, limit uint) ([]*app.ItemGroup, error) {
+	testItem := provider.NewItem("")
+	groupField, err := reflections.GetFieldTag(testItem, groupBy, "bson")
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't get item field, fname=%s", groupBy)
+	}
+	projection := bson.M{"provname": "$provname", "provbranch": "$provbranch", "id": "$id"}
+	sort := bson.D{}
+	for _, fname := range fields {
+		dbField, err := reflections.GetFieldTag(testItem, fname, "bson")
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get item field, fname=%s", fname)
+		}
+		projection[dbField] = "$" + dbField
+		sort = append(sort, bson.E{Key: dbField, Value: 1})
+	}
+	sort = append(sort, bson.E{Key: "id", Value: 1})
+
+	filter := bson.M(testItem.GetProviderFilter())
+	filter[groupField] = bson.M{"$nin": bson.A{nil, ""}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$project", Value: bson.M{"group": "$" + groupField, "item": projection}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$group",
+			"count": bson.M{"$sum": 1},
+			"items": bson.M{"$push": "$item"},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gte": int64(minCount)}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: int64(limit)}},
+	}
+	coll, err := s.collection(ctx, testItem)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't group items, property=%s", groupBy)
+	}
+	defer cursor.Close(ctx)
+
+	result := make([]*app.ItemGroup, 0)
+	for cursor.Next(ctx) {
+		var doc struct {
+			Value interface{} `bson:"_id"`
+			Count int         `bson:"count"`
+			Items []bson.Raw  `bson:"items"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, errors.Trace(err)
+		}
+		group := &app.ItemGroup{Value: doc.Value, Count: doc.Count, Items: make([]app.IItem, 0, len(doc.Items))}
+		for _, raw := range doc.Items {
+			item := provider.NewItem("")
+			if err := bson.Unmarshal(raw, item); err != nil {
+				return nil, errors.Trace(err)
+			}
+			group.Items = append(group.Items, item)
+		}
+		result = append(result, group)
+	}
+	return result, errors.Trace(cursor.Err())
+}
+
 func (s *ItemRepository) Close() error {
 	if s.client == nil {
 		return nil
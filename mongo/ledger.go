@@ -0,0 +1,174 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ app.IContainerLedger = (*ContainerLedger)(nil)
+
+type ContainerLedger struct {
+	client   *mongo.Client
+	config   *app.StorageConfig
+	collName string
+	coll     *mongo.Collection
+}
+
+const ledgerCollName = "ledger"
+
+func NewContainerLedger(conf *app.StorageConfig) (*ContainerLedger, error) {
+	clientOptions := options.Client().ApplyURI(conf.Uri)
+
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	// check connection
+	err = client.Ping(context.TODO(), nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	coll := client.Database(conf.DbName).Collection(ledgerCollName)
+	_, err = coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "providerkey", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "providerkey", Value: 1}, {Key: "seq", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create ledger indexes")
+	}
+
+	logrus.WithFields(logrus.Fields{}).Debug("container ledger initialized")
+
+	return &ContainerLedger{
+		client:   client,
+		config:   conf,
+		collName: ledgerCollName,
+		coll:     coll,
+	}, nil
+}
+
+func (l *ContainerLedger) mark(ctx context.Context, provKey string, container *app.ItemsContainer, set bson.M, inc bson.M) error {
+	filter := bson.M{
+		"providerkey": strings.ToLower(provKey),
+		"key":         container.String(),
+	}
+	set["container"] = container
+	set["seq"] = container.Uint()
+	set["updatedat"] = time.Now()
+	update := bson.M{"$set": set}
+	if inc != nil {
+		update["$inc"] = inc
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err := l.coll.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return errors.Annotatef(err, "can't update ledger, container=%s", container.String())
+	}
+	return nil
+}
+
+/*
+Job may be taken by worker before it's marked as queued, so status set by worker isn't overwritten:
+filter doesn't match such record and upsert fails with duplicate key error.
+Failed container (e.g. queued again by backfill) becomes queued, unless it failed after queuedAt,
+i.e. worker has failed the new job already.
+*/
+func (l *ContainerLedger) MarkQueued(ctx context.Context, provKey string, container *app.ItemsContainer, queuedAt time.Time) error {
+	filter := bson.M{
+		"providerkey": strings.ToLower(provKey),
+		"key":         container.String(),
+		"$or": bson.A{
+			bson.M{"status": bson.M{"$nin": bson.A{app.ContainerStatusProcessing, app.ContainerStatusDone, app.ContainerStatusFailed}}},
+			bson.M{"status": app.ContainerStatusFailed, "updatedat": bson.M{"$lt": queuedAt}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"status":    app.ContainerStatusQueued,
+		"container": container,
+		"seq":       container.Uint(),
+		"updatedat": time.Now(),
+	}}
+	_, err := l.coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		logrus.WithFields(logrus.Fields{"container": container.String()}).Debug("container is marked by worker already")
+		return nil
+	} else if err != nil {
+		return errors.Annotatef(err, "can't update ledger, container=%s", container.String())
+	}
+	return nil
+}
+
+func (l *ContainerLedger) MarkProcessing(ctx context.Context, provKey string, container *app.ItemsContainer) error {
+	return l.mark(ctx, provKey, container, bson.M{"status": app.ContainerStatusProcessing}, bson.M{"attempts": 1})
+}
+
+func (l *ContainerLedger) MarkDone(ctx context.Context, provKey string, container *app.ItemsContainer, itemsCount int) error {
+	set := bson.M{
+		"status":     app.ContainerStatusDone,
+		"itemscount": itemsCount,
+		"lasterror":  "",
+	}
+	return l.mark(ctx, provKey, container, set, nil)
+}
+
+func (l *ContainerLedger) MarkFailed(ctx context.Context, provKey string, container *app.ItemsContainer, reason error) error {
+	set := bson.M{
+		"status":    app.ContainerStatusFailed,
+		"lasterror": reason.Error(),
+	}
+	return l.mark(ctx, provKey, container, set, nil)
+}
+
+func (l *ContainerLedger) Walk(ctx context.Context, provKey string, from, to uint, fn func(record *app.ContainerRecord) error) error {
+	filter := bson.M{
+		"providerkey": strings.ToLower(provKey),
+		"seq":         bson.M{"$gte": from, "$lte": to},
+	}
+	opts := options.Find().SetSort(bson.M{"seq": 1})
+	cursor, err := l.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return errors.Annotate(err, "can't get ledger records")
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		record := &app.ContainerRecord{}
+		err := cursor.Decode(record)
+		if err != nil {
+			return errors.Annotate(err, "can't decode ledger record")
+		}
+		err = fn(record)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(cursor.Err())
+}
+
+func (l *ContainerLedger) Close() error {
+	if l.client == nil {
+		return nil
+	}
+	err := l.client.Disconnect(context.TODO())
+	if err != nil {
+		return errors.Annotate(err, "can't disconnect mongo client")
+	}
+	logrus.Info("container ledger closed")
+	return nil
+}
@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ app.ISnapshotStore = (*SnapshotStore)(nil)
+
+// SnapshotStore keeps property snapshots in "snapshot" collection, one document per item property and time
+type SnapshotStore struct {
+	client *mongo.Client
+	config *app.StorageConfig
+	coll   *mongo.Collection
+}
+
+const snapshotCollName = "snapshot"
+
+func NewSnapshotStore(conf *app.StorageConfig) (*SnapshotStore, error) {
+	clientOptions := options.Client().ApplyURI(conf.Uri)
+
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	// check connection
+	err = client.Ping(context.TODO(), nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't connect to mongo")
+	}
+
+	coll := client.Database(conf.DbName).Collection(snapshotCollName)
+	_, err = coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			//history of item property, the latest first
+			Keys:    bson.D{{Key: "provname", Value: 1}, {Key: "provbranch", Value: 1}, {Key: "id", Value: 1}, {Key: "property", Value: 1}, {Key: "takenat", Value: -1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create snapshot indexes")
+	}
+
+	logrus.WithFields(logrus.Fields{}).Debug("snapshot store initialized")
+
+	return &SnapshotStore{
+		client: client,
+		config: conf,
+		coll:   coll,
+	}, nil
+}
+
+func snapshotFilter(id *app.ItemId, property string) bson.M {
+	return bson.M{"provname": id.ProvName, "provbranch": id.ProvBranch, "id": id.Id, "property": property}
+}
+
+func (s *SnapshotStore) SaveSnapshot(ctx context.Context, snapshot *app.PropertySnapshot) error {
+	filter := snapshotFilter(snapshot.Item, snapshot.Property)
+	filter["takenat"] = snapshot.TakenAt
+	models := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$setOnInsert": snapshot}).SetUpsert(true),
+	}
+	_, err := upsertMany(ctx, s.coll, models)
+	if err != nil {
+		return errors.Annotatef(err, "can't save snapshot of property=%s, item=%s", snapshot.Property, snapshot.Item.String())
+	}
+	return nil
+}
+
+func (s *SnapshotStore) PruneSnapshots(ctx context.Context, id *app.ItemId, property string, keep int) error {
+	if keep < 1 {
+		return errors.Errorf("at least one snapshot must be kept, keep=%d", keep)
+	}
+	//the oldest snapshot which is kept
+	opts := options.FindOne().SetSort(bson.M{"takenat": -1}).SetSkip(int64(keep - 1)).SetProjection(bson.M{"takenat": 1})
+	var oldest app.PropertySnapshot
+	err := s.coll.FindOne(ctx, snapshotFilter(id, property), opts).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return errors.Annotatef(err, "can't find snapshots of property=%s, item=%s", property, id.String())
+	}
+
+	filter := snapshotFilter(id, property)
+	filter["takenat"] = bson.M{"$lt": oldest.TakenAt}
+	result, err := s.coll.DeleteMany(ctx, filter)
+	if err != nil {
+		return errors.Annotatef(err, "can't delete snapshots of property=%s, item=%s", property, id.String())
+	}
+	logrus.WithFields(logrus.Fields{
+		"item_id":  id.String(),
+		"property": property,
+		"deleted":  result.DeletedCount,
+	}).Debug("snapshots pruned")
+	return nil
+}
+
+func (s *SnapshotStore) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Disconnect(context.TODO())
+	if err != nil {
+		return errors.Annotate(err, "can't disconnect mongo client")
+	}
+	logrus.Info("snapshot store closed")
+	return nil
+}
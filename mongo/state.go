@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"purrproof/smartcrawl/app"
@@ -40,6 +41,17 @@ func NewAppStateStore(conf *app.StorageConfig) (*AppStateStore, error) {
 		return nil, errors.Annotate(err, "can't connect to mongo")
 	}
 
+	//one state per provider key and cursor, legacy state without provider key isn't indexed (see Migrate)
+	coll := client.Database(conf.DbName).Collection(appStateCollName)
+	_, err = coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "providerkey", Value: 1}, {Key: "cursor", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"providerkey": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create app state index")
+	}
+
 	logrus.WithFields(logrus.Fields{}).Info("AppStateStore initialized")
 
 	return &AppStateStore{
@@ -49,29 +61,47 @@ func NewAppStateStore(conf *app.StorageConfig) (*AppStateStore, error) {
 	}, nil
 }
 
-func (s *AppStateStore) Save(info *app.AppState) error {
-	coll := s.client.Database(s.config.DbName).Collection(s.collName)
+func (s *AppStateStore) coll() *mongo.Collection {
+	return s.client.Database(s.config.DbName).Collection(s.collName)
+}
+
+// provider key and cursor name identify state document
+func (s *AppStateStore) filter(provKey string, cursor ...string) bson.M {
+	cursorName := app.DefaultCursorName
+	if len(cursor) != 0 && cursor[0] != "" {
+		cursorName = cursor[0]
+	}
+	return bson.M{
+		"providerkey": strings.ToLower(provKey),
+		"cursor":      cursorName,
+	}
+}
+
+func (s *AppStateStore) Save(provKey string, info *app.AppState) error {
+	filter := s.filter(provKey, info.Cursor)
+	info.ProviderKey = filter["providerkey"].(string)
+	info.Cursor = filter["cursor"].(string)
 	info.UpdatedAt = time.Now()
-	filter := bson.D{}
-	update := bson.D{{"$set", info}}
+	update := bson.D{{Key: "$set", Value: info}}
 	opts := options.Update().SetUpsert(true)
-	_, err := coll.UpdateOne(context.TODO(), filter, update, opts)
+	_, err := s.coll().UpdateOne(context.TODO(), filter, update, opts)
 	if err != nil {
 		return errors.Annotate(err, "can't update record")
 	}
 	return nil
 }
 
-func (s *AppStateStore) Get() (*app.AppState, error) {
-	coll := s.client.Database(s.config.DbName).Collection(s.collName)
+func (s *AppStateStore) Get(provKey string, cursor ...string) (*app.AppState, error) {
 	var result *app.AppState
 
-	filter := bson.D{}
-	err := coll.FindOne(context.TODO(), filter).Decode(&result)
+	filter := s.filter(provKey, cursor...)
+	err := s.coll().FindOne(context.TODO(), filter).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			//state has not been saved yet, create new struct and return
 			state := &app.AppState{
+				ProviderKey:           filter["providerkey"].(string),
+				Cursor:                filter["cursor"].(string),
 				LatestQueuedContainer: nil,
 			}
 			return state, nil
@@ -81,6 +111,54 @@ func (s *AppStateStore) Get() (*app.AppState, error) {
 	return result, nil
 }
 
+func (s *AppStateStore) GetAll(provKey string) ([]*app.AppState, error) {
+	filter := bson.M{"providerkey": strings.ToLower(provKey)}
+	opts := options.Find().SetSort(bson.M{"cursor": 1})
+	cursor, err := s.coll().Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't get app states")
+	}
+	defer cursor.Close(context.TODO())
+
+	result := make([]*app.AppState, 0)
+	err = cursor.All(context.TODO(), &result)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't decode app states")
+	}
+	return result, nil
+}
+
+func (s *AppStateStore) Reset(provKey string, cursor ...string) error {
+	_, err := s.coll().DeleteOne(context.TODO(), s.filter(provKey, cursor...))
+	if err != nil {
+		return errors.Annotate(err, "can't reset app state")
+	}
+	return nil
+}
+
+/*
+Before per-provider cursors, state was a single document without provider key.
+It's assigned to given provider key and default cursor name.
+Returns false if there is nothing to migrate.
+*/
+func (s *AppStateStore) Migrate(provKey string) (bool, error) {
+	filter := s.filter(provKey)
+	count, err := s.coll().CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return false, errors.Annotate(err, "can't check app state")
+	} else if count > 0 {
+		return false, errors.Errorf("state already exists, provider=%s, cursor=%s", filter["providerkey"], filter["cursor"])
+	}
+
+	legacy := bson.M{"providerkey": bson.M{"$exists": false}}
+	update := bson.M{"$set": filter}
+	res, err := s.coll().UpdateOne(context.TODO(), legacy, update)
+	if err != nil {
+		return false, errors.Annotate(err, "can't migrate app state")
+	}
+	return res.ModifiedCount > 0, nil
+}
+
 func (s *AppStateStore) Close() error {
 	if s.client == nil {
 		return nil
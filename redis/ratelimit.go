@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Token bucket kept in Redis, so rate limit of provider is shared by all worker processes.
+Bucket is refilled by script using Redis clock, clocks of workers don't matter.
+Returns 0 if token is taken, otherwise milliseconds to wait before the next try.
+*/
+var tokenBucketScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+end
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait
+`)
+
+const keyPrefix = "smartcrawl:ratelimit:"
+
+var _ app.IRateLimiter = (*RateLimiter)(nil)
+
+type RateLimiter struct {
+	client *goredis.Client
+	key    string
+	config *app.RateLimitConfig
+}
+
+// name identifies bucket, e.g. provider key, workers with the same name share the limit
+func NewRateLimiter(qconf *app.QueueConfig, name string, conf *app.RateLimitConfig) *RateLimiter {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     qconf.Addr,
+		Username: qconf.User,
+		Password: qconf.Password,
+	})
+	logrus.WithFields(logrus.Fields{
+		"name":             name,
+		"requests_per_sec": conf.RequestsPerSec,
+		"burst":            conf.Burst,
+	}).Debug("rate limiter initialized")
+	return &RateLimiter{
+		client: client,
+		key:    keyPrefix + name,
+		config: conf,
+	}
+}
+
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		waitMs, err := tokenBucketScript.Run(ctx, l.client, []string{l.key}, l.config.RequestsPerSec, l.config.Burst).Int64()
+		if err != nil {
+			err = errors.Annotatef(err, "can't take rate limit token, key=%s", l.key)
+			return app.NewClassifiedError(app.ErrorKindTransient, err)
+		} else if waitMs <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(time.Duration(waitMs) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err := errors.Annotate(ctx.Err(), "rate limit wait is interrupted")
+			return app.NewClassifiedError(app.ErrorKindTransient, err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *RateLimiter) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ContractAddressForms(t *testing.T) {
+	provider := newZilliqaProvider("http://localhost:4201", 0)
+	for _, address := range []string{
+		"4baf5fada8e5db92c3d3242618c5b47133ae003c",
+		"0x4BAF5faDA8e5Db92C3d3242618c5B47133AE003C",
+		"0X4BAF5FADA8E5DB92C3D3242618C5B47133AE003C",
+		"zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7",
+	} {
+		contract := provider.NewItem(address).(*zilliqa.ZilliqaContract)
+		assert.Equal(t, "4baf5fada8e5db92c3d3242618c5b47133ae003c", contract.Id)
+
+		contract.CallAllRealtimeAutosetters(context.Background())
+		assert.Equal(t, "zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7", contract.AddressBech32)
+		assert.Equal(t, "0x4BAF5faDA8e5Db92C3d3242618c5B47133AE003C", contract.AddressChecksum)
+	}
+}
+
+func Test_NormalizeItemId(t *testing.T) {
+	provider := newZilliqaProvider("http://localhost:4201", 0)
+	contract := provider.NewItem("").(*zilliqa.ZilliqaContract)
+	contract.Id = "zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7"
+	assert.Nil(t, app.NormalizeItemId(contract))
+	assert.Equal(t, "4baf5fada8e5db92c3d3242618c5b47133ae003c", contract.Id)
+
+	invalid := provider.NewItem("zil1invalid")
+	assert.Equal(t, "zil1invalid", invalid.GetId().Id)
+	assert.NotNil(t, app.NormalizeItemId(invalid))
+	assert.NotNil(t, invalid.CallAutosetter(context.Background(), "AddressBech32"))
+
+	//items without several id forms are left as is
+	assert.Nil(t, app.NormalizeItemId(app.NewItem("Zilliqa", "1", "any")))
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/jsonrpc"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JsonRpcBatch(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []jsonrpc.Request
+		json.NewDecoder(r.Body).Decode(&reqs)
+		//responses in reverse order, the last request has no response
+		w.Write([]byte(fmt.Sprintf(`[{"id":%d,"jsonrpc":"2.0","error":{"code":-5,"message":"invalid address"}},{"id":"%d","jsonrpc":"2.0","result":"10"}]`,
+			reqs[1].Id, reqs[0].Id)))
+	}))
+	defer node.Close()
+
+	var height string
+	var address string
+	elems := []*jsonrpc.BatchElem{
+		{Method: "GetNumTxBlocks", Result: &height},
+		{Method: "GetContractAddressFromTransactionID", Params: []interface{}{"bad"}, Result: &address},
+		{Method: "GetNumTxBlocks"},
+	}
+	err := jsonrpc.NewClient(node.URL, time.Second).Batch(context.Background(), elems)
+	assert.Nil(t, err)
+	assert.Nil(t, elems[0].Error)
+	assert.Equal(t, "10", height)
+	assert.ErrorContains(t, elems[1].Error, "invalid address")
+	assert.ErrorContains(t, elems[2].Error, "no response in batch")
+}
+
+func Test_FetchContainerItemsBatch(t *testing.T) {
+	stub := &stubNode{deploys: 5, failures: map[string]int{"tx3": 2}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+
+	items, err := newZilliqaProvider(node.URL, 4).FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(items))
+	for i, item := range items {
+		contract := item.(*zilliqa.ZilliqaContract)
+		assert.Equal(t, fmt.Sprintf("addr_tx%d", i), contract.GetId().Id)
+		assert.Equal(t, uint32(1600000000), contract.Timestamp)
+	}
+	//tx bodies, batch of 4 and its 2 retries of tx3 only, batch of 2
+	assert.Equal(t, 5, stub.getRequests())
+}
+
+func Benchmark_FetchContainerItems(b *testing.B) {
+	stub := &stubNode{deploys: 30, latency: time.Millisecond, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	container := app.NewItemsContainer([]string{"100"})
+
+	for _, batchSize := range []int{1, 50} {
+		provider := newZilliqaProvider(node.URL, batchSize)
+		b.Run(fmt.Sprintf("batch_size_%d", batchSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := provider.FetchContainerItems(context.Background(), container)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Test_BatchSubState(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 2)
+
+	//the same contract in all forms
+	for _, address := range []string{
+		"0x4BAF5FADA8E5DB92C3D3242618C5B47133AE003C",
+		"4baf5fada8e5db92c3d3242618c5b47133ae003c",
+		"zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7",
+	} {
+		result, err := provider.BatchSubState(context.Background(), address, []string{"balances", "missing", "total_supply"})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(result))
+		assert.Nil(t, result["balances"].Error)
+		assert.Equal(t, map[string]interface{}{"0x4baf5fada8e5db92c3d3242618c5b47133ae003c": "100"}, result["balances"].Value)
+		assert.Equal(t, app.ErrorKindNotFound, app.GetErrorKind(result["missing"].Error))
+		assert.Nil(t, result["total_supply"].Error)
+	}
+
+	_, err := provider.BatchSubState(context.Background(), "0x1234", []string{"balances"})
+	assert.True(t, app.IsPermanentError(err))
+}
+
+func Test_StateCalls(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 2)
+	address := "zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7"
+
+	//calls go through endpoints of provider
+	state, err := provider.State(context.Background(), address)
+	assert.Nil(t, err)
+	assert.Contains(t, state, `"total_supply": "1000"`)
+	assert.Equal(t, 1, stub.getRequests())
+
+	substate, err := provider.SubState(context.Background(), address, "total_supply", []string{})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"total_supply": "1000"}`, substate)
+
+	_, err = provider.SubState(context.Background(), "0x1234", "total_supply", []string{})
+	assert.True(t, app.IsPermanentError(err))
+	assert.Equal(t, 2, stub.getRequests())
+}
+
+// GetNumTxBlocks is number of blocks, the latest block is the one before it
+func Test_LatestBlockId(t *testing.T) {
+	node := httptest.NewServer(&stubNode{})
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 2)
+
+	latest, err := provider.GetLatestBlockId(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint(100), latest)
+
+	//block 101 doesn't exist yet, so it isn't listed
+	list, err := provider.GetContainersList(context.Background(), 5, app.NewItemsContainer([]string{"98"}))
+	assert.Nil(t, err)
+	assert.Equal(t, []*app.ItemsContainer{app.NewItemsContainer([]string{"99"}), app.NewItemsContainer([]string{"100"})}, list)
+
+	list, err = provider.GetContainersList(context.Background(), 5, app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "jsonrpc": "2.0", "result": "0"})
+	}))
+	defer empty.Close()
+	_, err = newZilliqaProvider(empty.URL, 2).GetLatestBlockId(context.Background())
+	assert.NotNil(t, err)
+}
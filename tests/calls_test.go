@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/mongo"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func Test_FetchContainerCalls(t *testing.T) {
+	stub := &stubNode{deploys: 1, calls: 3, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+
+	calls, err := newZilliqaProvider(node.URL, 10).FetchContainerCalls(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	//deploy and payment aren't calls
+	assert.Equal(t, 3, len(calls))
+	call := calls[1]
+	assert.Equal(t, stubCalledContract, call.Item.Id)
+	assert.Equal(t, uint(100), call.Seq)
+	assert.Equal(t, "ctx1", call.Txid)
+	assert.Equal(t, "Transfer", call.Tag)
+	assert.Equal(t, "9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a", call.Sender)
+	assert.False(t, call.Success)
+	assert.True(t, calls[0].Success)
+	assert.Equal(t, uint64(517), call.Gas)
+	assert.Equal(t, "0", call.Amount)
+}
+
+func Test_ContainerProcessCalls(t *testing.T) {
+	providerKey := "mock"
+	container := app.NewItemsContainer([]string{"100"})
+	calls := []*app.ItemCall{
+		{Item: &app.ItemId{ProvName: "Mock", ProvBranch: "1", Id: "item1"}, Seq: 100, Txid: "tx1", Tag: "Transfer", Success: true},
+	}
+
+	provider := new(mocks.ItemProviderMock)
+	provider.On("FetchContainerItems", container).Return([]app.IItem{app.NewItem("Mock", "1", "item1")}, nil)
+	provider.On("FetchContainerCalls", container).Return(calls, nil).Once()
+	repository := new(mocks.CallRepositoryMock)
+	repository.On("SaveCalls", calls).Return(nil).Once()
+	//stats of saved items are set from stats counted before
+	repository.On("UpdateCallStats", []*app.ItemId{{ProvName: "Mock", ProvBranch: "1", Id: "item1"}}).Return(nil).Once()
+
+	thejob := job.NewMessageJobContainerProcess(providerKey, container)
+	thejob.SetItemProvider(provider)
+	thejob.SetItemRepository(new(mocks.ItemRepositoryMock))
+
+	//calls aren't tracked without repository
+	_, err := thejob.Execute(context.Background())
+	assert.Nil(t, err)
+	provider.AssertNotCalled(t, "FetchContainerCalls", container)
+
+	thejob.SetCallRepository(repository)
+	_, err = thejob.Execute(context.Background())
+	assert.Nil(t, err)
+	repository.AssertExpectations(t)
+
+	//container is failed, so it's processed again
+	provider.On("FetchContainerCalls", container).Return(nil, errors.New("node is down")).Once()
+	_, err = thejob.Execute(context.Background())
+	assert.NotNil(t, err)
+	repository.AssertNumberOfCalls(t, "SaveCalls", 1)
+}
+
+func Test_CallStats(t *testing.T) {
+	storage := getTestStorage(t)
+	items, err := mongo.NewItemRepository(storage)
+	assert.Nil(t, err)
+	defer items.Close()
+	repository, err := mongo.NewCallRepository(storage)
+	assert.Nil(t, err)
+	defer repository.Close()
+	provider := newZilliqaProvider("http://localhost:4201", 0)
+	ctx := context.Background()
+
+	called := provider.NewItem(stubCalledContract)
+	newCall := func(txid string, seq uint, sender string, success bool) *app.ItemCall {
+		return &app.ItemCall{Item: called.GetId(), Seq: seq, Txid: txid, Tag: "Transfer", Sender: sender, Success: success}
+	}
+	getStats := func() *app.CallStats {
+		stored, err := items.Get(ctx, provider.NewItem(stubCalledContract))
+		assert.Nil(t, err)
+		return stored.(*zilliqa.ZilliqaContract).Calls
+	}
+	calls := []*app.ItemCall{
+		newCall("tx1", 100, "0x1", true),
+		newCall("tx2", 100, "0x2", false),
+		newCall("tx3", 101, "0x1", true),
+	}
+
+	//contract isn't crawled yet, calls are counted, but contract isn't created
+	assert.Nil(t, repository.SaveCalls(ctx, calls))
+	stored, err := items.Get(ctx, provider.NewItem(stubCalledContract))
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	//contract is saved later, it gets stats counted before
+	assert.Nil(t, items.Save(ctx, called))
+	assert.Nil(t, repository.UpdateCallStats(ctx, []*app.ItemId{called.GetId()}))
+	expected := &app.CallStats{Total: 3, Failed: 1, Callers: 2, LastSeq: 101}
+	assert.Equal(t, expected, getStats())
+
+	//container processed again doesn't count its calls twice
+	assert.Nil(t, repository.SaveCalls(ctx, calls[:2]))
+	assert.Equal(t, expected, getStats())
+
+	assert.Nil(t, repository.SaveCalls(ctx, []*app.ItemCall{newCall("tx4", 102, "0x3", false)}))
+	assert.Equal(t, &app.CallStats{Total: 4, Failed: 2, Callers: 3, LastSeq: 102}, getStats())
+
+	//job failed after calls were stored, its retry counts them
+	client, err := mongo_driver.Connect(ctx, options.Client().ApplyURI(storage.Uri))
+	assert.Nil(t, err)
+	defer client.Disconnect(ctx)
+	pending := newCall("tx5", 103, "0x4", true)
+	doc := bson.M{"provname": called.GetId().ProvName, "provbranch": called.GetId().ProvBranch, "id": called.GetId().Id,
+		"txid": "tx5", "seq": 103, "sender": "0x4", "success": true, "pending": true}
+	_, err = client.Database(storage.DbName).Collection("call").InsertOne(ctx, doc)
+	assert.Nil(t, err)
+	assert.Nil(t, repository.SaveCalls(ctx, []*app.ItemCall{pending}))
+	assert.Equal(t, &app.CallStats{Total: 5, Failed: 2, Callers: 4, LastSeq: 103}, getStats())
+}
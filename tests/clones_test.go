@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/mongo"
+	"purrproof/smartcrawl/zilliqa"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// storage of new database, test is skipped if mongo isn't available
+func getTestStorage(t *testing.T) *app.StorageConfig {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	conn, err := net.DialTimeout("tcp", strings.TrimPrefix(uri, "mongodb://"), 200*time.Millisecond)
+	if err != nil {
+		t.Skip("mongo is not available: ", uri)
+	}
+	conn.Close()
+	return &app.StorageConfig{Uri: uri, DbName: "test_" + strconv.FormatInt(time.Now().UnixNano(), 10)}
+}
+
+func Test_ItemGroups(t *testing.T) {
+	repository, err := mongo.NewItemRepository(getTestStorage(t))
+	assert.Nil(t, err)
+	defer repository.Close()
+	provider := newZilliqaProvider("http://localhost:4201", 0)
+
+	//3 clones deployed in 2 days, 2 clones, single contract
+	for i, hash := range []string{"0xa", "0xb", "0xa", "0xc", "0xa", "0xb"} {
+		contract := provider.NewItem(fmt.Sprintf("%040d", i)).(*zilliqa.ZilliqaContract)
+		contract.CodeHash = hash
+		contract.Block = uint(100 - i)
+		contract.Timestamp = uint32(1600000000 + i*43200)
+		assert.Nil(t, repository.Save(context.Background(), contract))
+	}
+
+	groups, err := repository.GetGroups(context.Background(), provider, "CodeHash", []string{"Block", "Timestamp"}, 2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, "0xa", groups[0].Value)
+	assert.Equal(t, 3, groups[0].Count)
+	//the first deployed has the lowest block
+	first := groups[0].Items[0].(*zilliqa.ZilliqaContract)
+	assert.Equal(t, fmt.Sprintf("%040d", 4), first.Id)
+	assert.Equal(t, uint(96), first.Block)
+	assert.Equal(t, "", first.Code)
+	assert.Equal(t, "0xb", groups[1].Value)
+	assert.Equal(t, 2, groups[1].Count)
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_SlowProviderTimeout(t *testing.T) {
+	node := newSlowNode()
+	defer node.Close()
+
+	provider := newZilliqaProvider(node.URL, 0)
+	container := app.NewItemsContainer([]string{"100"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	items, err := provider.FetchContainerItems(ctx, container)
+	assert.Nil(t, items)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
+
+func Test_SlowProviderRetryCancel(t *testing.T) {
+	//network errors are retried, waiting between attempts must be interrupted too
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer node.Close()
+
+	//cancel comes while waiting for retry
+	provider, err := zilliqa.NewZilliqaBlockchain(&zilliqa.ZilliqaConfig{
+		Id:      "zilliqa",
+		ChainId: "1",
+		Api: &zilliqa.ZilliqaApiConfig{
+			HttpUrl: node.URL,
+			Retry:   &helpers.RetryConfig{DelayMs: 1000},
+		},
+	})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err = provider.GetContainersList(ctx, 10, nil)
+	assert.NotNil(t, err)
+	_, err = provider.FetchContainerItems(ctx, app.NewItemsContainer([]string{"100"}))
+	assert.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
+
+func Test_ContainerProcessTimeout(t *testing.T) {
+	node := newSlowNode()
+	defer node.Close()
+
+	providerKey := "zilliqa"
+	container := app.NewItemsContainer([]string{"100"})
+
+	ledger := new(mocks.ContainerLedgerMock)
+	ledger.On("MarkProcessing", providerKey, container).Return(nil)
+	ledger.On("MarkFailed", providerKey, container, mock.Anything).Return(nil).Once()
+
+	thejob := job.NewMessageJobContainerProcess(providerKey, container)
+	thejob.SetItemProvider(newZilliqaProvider(node.URL, 0))
+	thejob.SetItemRepository(new(mocks.ItemRepositoryMock))
+	thejob.SetContainerLedger(ledger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	newJobs, err := thejob.Execute(ctx)
+	assert.Nil(t, newJobs)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(started), 2*time.Second)
+	//failure is recorded even though job context is done
+	ledger.AssertCalled(t, "MarkFailed", providerKey, container, mock.Anything)
+}
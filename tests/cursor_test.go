@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/mongo"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func Test_AppStateStore(t *testing.T) {
+	storage := getTestStorage(t)
+	store, err := mongo.NewAppStateStore(storage)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	//nothing saved yet
+	state, err := store.Get("zilmain")
+	assert.Nil(t, err)
+	assert.Equal(t, "zilmain", state.ProviderKey)
+	assert.Equal(t, app.DefaultCursorName, state.Cursor)
+	assert.Nil(t, state.LatestQueuedContainer)
+
+	//providers and cursors don't overwrite each other, provider key isn't case sensitive
+	assert.Nil(t, store.Save("ZilMain", &app.AppState{LatestQueuedContainer: app.NewItemsContainer([]string{"100"})}))
+	assert.Nil(t, store.Save("zilmain", &app.AppState{Cursor: "backfill", LatestQueuedContainer: app.NewItemsContainer([]string{"5"})}))
+	assert.Nil(t, store.Save("ziltest", &app.AppState{LatestQueuedContainer: app.NewItemsContainer([]string{"7"})}))
+	assert.Nil(t, store.Save("zilmain", &app.AppState{LatestQueuedContainer: app.NewItemsContainer([]string{"101"})}))
+
+	state, err = store.Get("zilmain")
+	assert.Nil(t, err)
+	assert.Equal(t, "101", state.LatestQueuedContainer.String())
+	state, err = store.Get("zilmain", "backfill")
+	assert.Nil(t, err)
+	assert.Equal(t, "5", state.LatestQueuedContainer.String())
+	states, err := store.GetAll("zilmain")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(states))
+	assert.Equal(t, "backfill", states[0].Cursor)
+
+	//state is unique by provider key and cursor
+	client, err := mongo_driver.Connect(context.Background(), options.Client().ApplyURI(storage.Uri))
+	assert.Nil(t, err)
+	defer client.Disconnect(context.Background())
+	coll := client.Database(storage.DbName).Collection("state")
+	_, err = coll.InsertOne(context.Background(), bson.M{"providerkey": "zilmain", "cursor": app.DefaultCursorName})
+	assert.True(t, mongo_driver.IsDuplicateKeyError(err))
+
+	assert.Nil(t, store.Reset("zilmain", "backfill"))
+	states, err = store.GetAll("zilmain")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(states))
+
+	//legacy state without provider key
+	_, err = coll.InsertOne(context.Background(), bson.M{"latestqueuedcontainer": bson.M{"id": bson.A{"42"}}})
+	assert.Nil(t, err)
+	_, err = store.Migrate("zilmain")
+	assert.NotNil(t, err)
+	migrated, err := store.Migrate("zilold")
+	assert.Nil(t, err)
+	assert.True(t, migrated)
+	state, err = store.Get("zilold")
+	assert.Nil(t, err)
+	assert.Equal(t, "42", state.LatestQueuedContainer.String())
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JobUniqueId(t *testing.T) {
+	container := app.NewItemsContainer([]string{"100"})
+
+	//same container of same provider => same id
+	id := job.NewMessageJobContainerProcess("zilmain", container).GetUniqueId()
+	assert.Equal(t, "job:container:process:zilmain:100", id)
+	assert.Equal(t, id, job.NewMessageJobContainerProcess("ZilMain", app.NewItemsContainer([]string{"100"})).GetUniqueId())
+	assert.NotEqual(t, id, job.NewMessageJobContainerProcess("zildev", container).GetUniqueId())
+	assert.NotEqual(t, id, job.NewMessageJobContainerProcess("zilmain", app.NewItemsContainer([]string{"101"})).GetUniqueId())
+
+	itemId := &app.ItemId{ProvName: "Zilliqa", ProvBranch: "1", Id: "abc"}
+	id = job.NewMessageJobPropertySet("zilmain", itemId, "Name").GetUniqueId()
+	assert.Equal(t, "job:property:set:zilmain:Zilliqa_1_abc:Name", id)
+	assert.NotEqual(t, id, job.NewMessageJobPropertySet("zilmain", itemId, "Library").GetUniqueId())
+
+	//incomplete job isn't deduplicated
+	assert.Equal(t, "", job.NewMessageJobContainerProcess("zilmain", nil).GetUniqueId())
+	assert.Equal(t, "", job.NewMessageJobPropertySet("zilmain", nil, "Name").GetUniqueId())
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ContractCreator(t *testing.T) {
+	stub := &stubNode{deploys: 1, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+
+	items, err := newZilliqaProvider(node.URL, 10).FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	contract := items[0].(*zilliqa.ZilliqaContract)
+	//creator is in the form of contract Id, so deployer's contracts are found by its address
+	assert.Equal(t, "9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a", contract.Creator)
+	assert.Equal(t, "zil1n0lvw9dxh4jcljmzkruvexl69t08zs62ds9ats", contract.CreatorBech32)
+	assert.False(t, contract.Internal)
+	assert.Equal(t, "", contract.CreatorContract)
+}
+
+func Test_DiscoverCreations(t *testing.T) {
+	internal := "0x1111111111111111111111111111111111111111"
+	stub := &stubNode{
+		deploys:       1,
+		failedDeploys: 2,
+		//the first one is created in another block, the second one isn't contract
+		referenced: []string{"0x2222222222222222222222222222222222222222", "3333333333333333333333333333333333333333", internal},
+		creations: map[string]string{
+			"addr_ftx1": "100",
+			"1111111111111111111111111111111111111111": "100",
+			"2222222222222222222222222222222222222222": "42",
+		},
+		failures: map[string]int{},
+	}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+	container := app.NewItemsContainer([]string{"100"})
+
+	//failed deploy which created contract anyway
+	items, err := provider.FetchContainerItems(context.Background(), container)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "addr_tx0", items[0].GetId().Id)
+	retried := items[1].(*zilliqa.ZilliqaContract)
+	assert.Equal(t, "addr_ftx1", retried.Id)
+	assert.Equal(t, "scilla_version 0 (* failed *)", retried.Code)
+	assert.False(t, retried.Internal)
+
+	//contract created by contract
+	provider.Config.DiscoverInternal = true
+	items, err = provider.FetchContainerItems(context.Background(), container)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(items))
+	created := items[2].(*zilliqa.ZilliqaContract)
+	assert.Equal(t, "1111111111111111111111111111111111111111", created.Id)
+	assert.True(t, created.Internal)
+	assert.Equal(t, "calltx", created.Txid)
+	assert.Equal(t, "scilla_version 0 contract Internal()", created.Code)
+	assert.Equal(t, uint32(1600000000), created.Timestamp)
+	assert.Equal(t, "9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a", created.Creator)
+	//called contract created it
+	assert.Equal(t, "4baf5fada8e5db92c3d3242618c5b47133ae003c", created.CreatorContract)
+}
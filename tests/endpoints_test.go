@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
+	"purrproof/smartcrawl/zilliqa"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EndpointPoolWeights(t *testing.T) {
+	var calls1, calls2 int32
+	node1 := newCountingNode(&calls1, http.StatusOK)
+	defer node1.Close()
+	node2 := newCountingNode(&calls2, http.StatusOK)
+	defer node2.Close()
+
+	endpoints := []helpers.EndpointConfig{{HttpUrl: node1.URL, Weight: 3}, {HttpUrl: node2.URL}}
+	pool, err := helpers.NewEndpointPool("weights", endpoints, time.Second, nil, nil)
+	assert.Nil(t, err)
+
+	for i := 0; i < 8; i++ {
+		err := pool.Do(context.Background(), func(ctx context.Context, client *jsonrpc.Client) error {
+			return client.Call(ctx, "GetNumTxBlocks", nil)
+		})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls1))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls2))
+
+	stats := pool.Endpoints()[0].Stats()
+	assert.Equal(t, uint64(6), stats.Calls)
+	assert.Equal(t, uint64(0), stats.Errors)
+}
+
+func Test_EndpointFailover(t *testing.T) {
+	var calls1, calls2 int32
+	down := newCountingNode(&calls1, http.StatusBadGateway)
+	defer down.Close()
+	up := newCountingNode(&calls2, http.StatusOK)
+	defer up.Close()
+
+	provider, err := zilliqa.NewZilliqaBlockchain(&zilliqa.ZilliqaConfig{
+		Id:      "zilliqa",
+		ChainId: "1",
+		Api: &zilliqa.ZilliqaApiConfig{
+			Endpoints:      []helpers.EndpointConfig{{HttpUrl: down.URL, Weight: 10}, {HttpUrl: up.URL}},
+			Retry:          &helpers.RetryConfig{DelayMs: 1},
+			CircuitBreaker: &helpers.CircuitBreakerConfig{FailureThreshold: 1, OpenSec: 60},
+		},
+	})
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	for i := 0; i < 5; i++ {
+		blockId, err := provider.GetLatestBlockId(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, uint(9), blockId)
+	}
+	//breaker of failed endpoint is open, the rest of calls go to healthy one
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls1))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls2))
+	assert.Equal(t, uint64(1), provider.Endpoints.Endpoints()[0].Stats().Errors)
+}
+
+func Test_EndpointHealthCheck(t *testing.T) {
+	var calls1, calls2 int32
+	down := newCountingNode(&calls1, http.StatusServiceUnavailable)
+	defer down.Close()
+	up := newCountingNode(&calls2, http.StatusOK)
+	defer up.Close()
+
+	endpoints := []helpers.EndpointConfig{{HttpUrl: down.URL}, {HttpUrl: up.URL}}
+	pool, err := helpers.NewEndpointPool("health", endpoints, time.Second, nil, nil)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	pool.RunHealthCheck(ctx, 50*time.Millisecond, func(ctx context.Context, client *jsonrpc.Client) error {
+		return client.Call(ctx, "GetNumTxBlocks", nil)
+	})
+	assert.Greater(t, pool.Endpoints()[0].Breaker.OpenFor(), time.Duration(0))
+	assert.Equal(t, time.Duration(0), pool.Endpoints()[1].Breaker.OpenFor())
+
+	endpoint, err := pool.Next(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, up.URL, endpoint.Url)
+}
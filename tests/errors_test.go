@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
+	"purrproof/smartcrawl/memory"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ErrorKind(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind app.ErrorKind
+	}{
+		{&jsonrpc.HTTPError{StatusCode: 429}, app.ErrorKindRateLimited},
+		{&jsonrpc.HTTPError{StatusCode: 502}, app.ErrorKindTransient},
+		{&jsonrpc.HTTPError{StatusCode: 404}, app.ErrorKindPermanent},
+		{&jsonrpc.Error{Code: helpers.RpcMethodNotFound, Message: "Method not found"}, app.ErrorKindPermanent},
+		{&jsonrpc.Error{Code: helpers.RpcInternalError}, app.ErrorKindTransient},
+		{&jsonrpc.Error{Code: -1, Message: "something"}, app.ErrorKindUnknown},
+		{context.DeadlineExceeded, app.ErrorKindTransient},
+		{errors.New("timeout, but only in message"), app.ErrorKindUnknown},
+		//kind survives annotations
+		{errors.Annotate(errors.Trace(&jsonrpc.HTTPError{StatusCode: 503}), "can't call"), app.ErrorKindTransient},
+		{errors.Annotate(app.NewClassifiedError(app.ErrorKindPermanent, errors.New("bad data")), "job failed"), app.ErrorKindPermanent},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.kind, helpers.GetErrorKind(c.err), c.err.Error())
+	}
+
+	//transport error
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	node.Close()
+	err := jsonrpc.NewClient(node.URL, time.Second).Call(context.Background(), "GetNumTxBlocks", nil)
+	assert.Equal(t, app.ErrorKindTransient, helpers.GetErrorKind(err))
+}
+
+func Test_ZilliqaErrorKind(t *testing.T) {
+	container := app.NewItemsContainer([]string{"100"})
+
+	//node reports empty block as error
+	node := newErrorNode(-1, "TxBlock has no transactions")
+	defer node.Close()
+	items, err := newZilliqaProvider(node.URL, 0).FetchContainerItems(context.Background(), container)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(items))
+
+	node2 := newErrorNode(-8, "Address size not appropriate")
+	defer node2.Close()
+	//transactions are cached in container for one job, so the other job has its own container
+	_, err = newZilliqaProvider(node2.URL, 0).FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.True(t, app.IsPermanentError(err), "unexpected error: %v", err)
+}
+
+func Test_MemoryQueuePermanentError(t *testing.T) {
+	conf := &app.QueueConfig{
+		Job: &app.JobConfig{MaxRetry: intPtr(3)},
+	}
+	attempts := 0
+	handler := func(ctx context.Context, payload []byte) ([]app.IJob, error) {
+		attempts++
+		err := app.NewClassifiedError(app.ErrorKindPermanent, errors.New("invalid container"))
+		return nil, errors.Annotate(err, "can't execute job")
+	}
+	jobQueue, err := memory.NewJobQueue(conf, handler)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	_, err = jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{"1"})))
+	assert.Nil(t, err)
+	err = jobQueue.Process(job.JobTypeContainerProcess, 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FetchContainerEvents(t *testing.T) {
+	internal := "0x1111111111111111111111111111111111111111"
+	stub := &stubNode{deploys: 1, calls: 3, referenced: []string{internal}, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+
+	events, err := newZilliqaProvider(node.URL, 10).FetchContainerEvents(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	//event of internal contract and events of two successful calls
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "1111111111111111111111111111111111111111", events[0].Item.Id)
+	assert.Equal(t, "Created", events[0].Name)
+	assert.Equal(t, map[string]interface{}{}, events[0].Params)
+
+	event := events[2]
+	assert.Equal(t, stubCalledContract, event.Item.Id)
+	assert.Equal(t, uint(100), event.Seq)
+	assert.Equal(t, "ctx2", event.Txid)
+	assert.Equal(t, uint(0), event.Index)
+	assert.Equal(t, "TransferSuccess", event.Name)
+	assert.Equal(t, map[string]interface{}{
+		"sender": "0x9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a",
+		"amount": "10",
+	}, event.Params)
+}
+
+func Test_ContainerProcessEvents(t *testing.T) {
+	container := app.NewItemsContainer([]string{"100"})
+	events := []*app.ItemEvent{
+		{Item: &app.ItemId{ProvName: "Mock", ProvBranch: "1", Id: "item1"}, Seq: 100, Txid: "tx1", Name: "Minted"},
+	}
+
+	provider := new(mocks.ItemProviderMock)
+	provider.On("FetchContainerItems", container).Return([]app.IItem{}, nil)
+	provider.On("FetchContainerEvents", container).Return(events, nil).Once()
+	repository := new(mocks.EventRepositoryMock)
+	repository.On("SaveEvents", events).Return(nil).Once()
+
+	thejob := job.NewMessageJobContainerProcess("mock", container)
+	thejob.SetItemProvider(provider)
+	thejob.SetItemRepository(new(mocks.ItemRepositoryMock))
+	thejob.SetEventRepository(repository)
+
+	_, err := thejob.Execute(context.Background())
+	assert.Nil(t, err)
+	provider.AssertExpectations(t)
+	repository.AssertExpectations(t)
+}
+
+func Test_ContainerTransactionsFetchedOnce(t *testing.T) {
+	stub := &stubNode{deploys: 1, calls: 2, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+	container := app.NewItemsContainer([]string{"100"})
+
+	_, err := provider.FetchContainerItems(context.Background(), container)
+	assert.Nil(t, err)
+	//block bodies and batch with timestamp and contract address
+	assert.Equal(t, 2, stub.getRequests())
+
+	//calls and events of the same job are extracted from transactions fetched for items
+	calls, err := provider.FetchContainerCalls(context.Background(), container)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(calls))
+	events, err := provider.FetchContainerEvents(context.Background(), container)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, 2, stub.getRequests())
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/evm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EvmBlockchain(t *testing.T) {
+	server := newEvmStubServer(t, newEvmReceipt())
+	defer server.Close()
+
+	provider := newEvmProvider(server.URL)
+
+	//latest block is 0x11=17, so only 2 blocks are available after block 15
+	list, err := provider.GetContainersList(context.Background(), 10, app.NewItemsContainer([]string{"15"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, "16", list[0].String())
+
+	items, err := provider.FetchContainerItems(context.Background(), list[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+
+	contract := items[0].(*evm.EvmContract)
+	assert.Equal(t, evmContractAddr, contract.Id)
+	assert.Equal(t, evmCreator, contract.Creator)
+	assert.Equal(t, evmDeployTxid, contract.Txid)
+	assert.Equal(t, uint(16), contract.Block)
+	assert.Equal(t, uint32(0x6400a8c0), contract.Timestamp)
+
+	contract.CallAllRealtimeAutosetters(context.Background())
+	assert.Equal(t, 5, contract.SizeBytes)
+	assert.Equal(t, evmCodeHash, contract.CodeHash)
+}
+
+func Test_EvmReceiptStatus(t *testing.T) {
+	container := app.NewItemsContainer([]string{"16"})
+
+	//pre-Byzantium receipt has state root instead of status
+	receipt := newEvmReceipt()
+	delete(receipt, "status")
+	receipt["root"] = "0x96b8b8d4a1a2b1e0cd2cbc3c1c7e3c6c6f2d5f1d0e4e2b3c9a7f4b1e5d2c3a4b"
+	server := newEvmStubServer(t, receipt)
+	items, err := newEvmProvider(server.URL).FetchContainerItems(context.Background(), container)
+	server.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+
+	//failed deploy
+	receipt = newEvmReceipt()
+	receipt["status"] = "0x0"
+	server = newEvmStubServer(t, receipt)
+	items, err = newEvmProvider(server.URL).FetchContainerItems(context.Background(), container)
+	server.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(items))
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/asynq"
+	"purrproof/smartcrawl/memory"
+	"purrproof/smartcrawl/mocks"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	asynq_lib "github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// number of containers requested from provider depends on free place in queue
+func Test_FollowPollBackPressure(t *testing.T) {
+	providerKey := "mock"
+	latest := app.NewItemsContainer([]string{"10"})
+	next := app.NewItemsContainer([]string{"11"})
+
+	cases := []struct {
+		name     string
+		depth    int
+		maxDepth int
+		limit    uint
+		//0 means provider isn't polled
+		expected uint
+	}{
+		{name: "queue is full", depth: 10, maxDepth: 10, limit: 5},
+		{name: "queue is over limit", depth: 12, maxDepth: 10, limit: 5},
+		{name: "free place less than limit", depth: 7, maxDepth: 10, limit: 5, expected: 3},
+		{name: "free place more than limit", depth: 2, maxDepth: 10, limit: 5, expected: 5},
+		{name: "no max depth", depth: 100, maxDepth: 0, limit: 5, expected: 5},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := &app.AppState{ProviderKey: providerKey, LatestQueuedContainer: latest}
+			stateStore := new(mocks.AppStateStoreMock)
+			stateStore.On("Get", providerKey, []string{"default"}).Return(state, nil)
+			stateStore.On("Save", providerKey, state).Return(nil)
+
+			jobQueue := new(mocks.JobQueueMock)
+			jobQueue.On("GetQueueDepth", job.JobTypeContainerProcess).Return(tc.depth, nil)
+			jobQueue.On("Add", mock.Anything).Return(&app.JobInfo{Id: "1", Queue: job.JobTypeContainerProcess}, nil)
+
+			ledger := new(mocks.ContainerLedgerMock)
+			ledger.On("MarkQueued", providerKey, next, mock.Anything).Return(nil)
+
+			provider := new(mocks.ItemProviderMock)
+			provider.On("GetContainersList", tc.expected, latest).Return([]*app.ItemsContainer{next}, nil)
+
+			follow := &app.FollowConfig{Limit: tc.limit, MaxQueueDepth: tc.maxDepth}
+			queued, err := job.FollowPoll(context.Background(), providerKey, provider, jobQueue, ledger, stateStore, "default", follow)
+			assert.Nil(t, err)
+
+			if tc.maxDepth == 0 {
+				jobQueue.AssertNotCalled(t, "GetQueueDepth", mock.Anything)
+			}
+			if tc.expected == 0 {
+				assert.Equal(t, 0, queued)
+				provider.AssertNotCalled(t, "GetContainersList", mock.Anything, mock.Anything)
+				jobQueue.AssertNotCalled(t, "Add", mock.Anything)
+				return
+			}
+			assert.Equal(t, 1, queued)
+			provider.AssertExpectations(t)
+			ledger.AssertExpectations(t)
+			//cursor is moved to queued container
+			assert.Equal(t, next, state.LatestQueuedContainer)
+		})
+	}
+}
+
+// jobs which are waiting and processing are in depth, finished ones aren't
+func Test_MemoryQueueDepth(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := func(ctx context.Context, payload []byte) ([]app.IJob, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}
+	jobQueue, err := memory.NewJobQueue(&app.QueueConfig{}, handler)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	depth, err := jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, depth)
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{id})))
+		assert.Nil(t, err)
+	}
+	depth, _ = jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Equal(t, 3, depth)
+
+	done := make(chan error)
+	go func() {
+		done <- jobQueue.Process(job.JobTypeContainerProcess, 1)
+	}()
+	//active job is still in depth
+	<-started
+	depth, _ = jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Equal(t, 3, depth)
+
+	close(release)
+	assert.Nil(t, <-done)
+	depth, _ = jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Equal(t, 0, depth)
+}
+
+func Test_AsynqQueueDepth(t *testing.T) {
+	conf := &app.QueueConfig{Addr: getTestRedisAddr(t)}
+	jobQueue, err := asynq.NewJobQueue(conf, nil)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	queueName := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	inspector := asynq_lib.NewInspector(asynq_lib.RedisClientOpt{Addr: conf.Addr})
+	defer inspector.Close()
+	defer inspector.DeleteQueue(queueName, true)
+
+	//queue which doesn't exist is empty
+	depth, err := jobQueue.GetQueueDepth(queueName)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, depth)
+
+	for _, id := range []string{"1", "2"} {
+		_, err := jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{id})), queueName)
+		assert.Nil(t, err)
+	}
+	depth, err = jobQueue.GetQueueDepth(queueName)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, depth)
+}
+
+func Test_AsynqConcurrentAdd(t *testing.T) {
+	conf := &app.QueueConfig{Addr: getTestRedisAddr(t)}
+	jobQueue, err := asynq.NewJobQueue(conf, nil)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	queueName := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	inspector := asynq_lib.NewInspector(asynq_lib.RedisClientOpt{Addr: conf.Addr})
+	defer inspector.Close()
+	defer inspector.DeleteQueue(queueName, true)
+
+	//workers add follow-up jobs at once, the same job is a duplicate for all but one of them
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{strconv.Itoa(i % 2)})), queueName)
+			assert.Nil(t, err)
+			_, err = jobQueue.GetQueueDepth(queueName)
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	depth, err := jobQueue.GetQueueDepth(queueName)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, depth)
+}
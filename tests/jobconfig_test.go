@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"purrproof/smartcrawl/app"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JobConfigOverrides(t *testing.T) {
+	//test config, values of shipped config.json are tuned for production
+	appConfig, err := app.NewConfig("testdata/jobconfig")
+	assert.Nil(t, err)
+	qconf := appConfig.Queue
+
+	//default
+	jconf := qconf.GetJobConfig("job:other")
+	assert.Equal(t, 1, jconf.Priority)
+	assert.Equal(t, 10, jconf.TimeoutSec)
+	maxRetry, defined := jconf.GetMaxRetry()
+	assert.True(t, defined)
+	assert.Equal(t, 3, maxRetry)
+
+	//exact name, only priority is overridden
+	jconf = qconf.GetJobConfig("job:container:process")
+	assert.Equal(t, 2, jconf.Priority)
+	assert.Equal(t, 10, jconf.TimeoutSec)
+
+	//pattern, property names are case sensitive, config keys are not
+	jconf = qconf.GetJobConfig("job:property:set:Name")
+	assert.Equal(t, 1, jconf.Priority)
+	assert.Equal(t, 20, jconf.TimeoutSec)
+	maxRetry, _ = jconf.GetMaxRetry()
+	assert.Equal(t, 4, maxRetry)
+
+	//the longest pattern wins
+	qconf = &app.QueueConfig{
+		Job: &app.JobConfig{TimeoutSec: 1},
+		Jobs: map[string]*app.JobConfig{
+			"job:*":              {TimeoutSec: 2},
+			"job:property:set:*": {TimeoutSec: 3},
+		},
+	}
+	assert.Equal(t, 3, qconf.GetJobConfig("job:property:set:Name").TimeoutSec)
+	assert.Equal(t, 2, qconf.GetJobConfig("job:container:process").TimeoutSec)
+	assert.Equal(t, 1, qconf.GetJobConfig("other").TimeoutSec)
+
+	//no retries is a setting, not a default
+	qconf = &app.QueueConfig{
+		Job:  &app.JobConfig{MaxRetry: intPtr(5)},
+		Jobs: map[string]*app.JobConfig{"job:container:process": {MaxRetry: intPtr(0)}, "job:property:set:*": {TimeoutSec: 3}},
+	}
+	maxRetry, defined = qconf.GetJobConfig("job:container:process").GetMaxRetry()
+	assert.True(t, defined)
+	assert.Equal(t, 0, maxRetry)
+	maxRetry, _ = qconf.GetJobConfig("job:property:set:State").GetMaxRetry()
+	assert.Equal(t, 5, maxRetry)
+	_, defined = (&app.QueueConfig{}).GetJobConfig("job:container:process").GetMaxRetry()
+	assert.False(t, defined)
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func Test_JobConfigRetryDelay(t *testing.T) {
+	jconf := &app.JobConfig{
+		Backoff:          app.BackoffExponential,
+		RetryDelaySec:    10,
+		RetryDelayMaxSec: 60,
+	}
+	delay, defined := jconf.RetryDelay(0)
+	assert.True(t, defined)
+	assert.Equal(t, 10*time.Second, delay)
+	delay, _ = jconf.RetryDelay(2)
+	assert.Equal(t, 40*time.Second, delay)
+	delay, _ = jconf.RetryDelay(10)
+	assert.Equal(t, 60*time.Second, delay)
+
+	jconf.Backoff = app.BackoffFixed
+	delay, _ = jconf.RetryDelay(10)
+	assert.Equal(t, 10*time.Second, delay)
+
+	jconf.Backoff = app.BackoffDefault
+	_, defined = jconf.RetryDelay(1)
+	assert.False(t, defined)
+
+	jconf.Backoff = "linear"
+	assert.NotNil(t, jconf.Validate())
+}
+
+func Test_JobConfigMaxRetryDelay(t *testing.T) {
+	//asynq default backoff and MaxRetry
+	jconf := &app.JobConfig{}
+	assert.Equal(t, time.Duration(24*24*24*24+15+30*25)*time.Second, jconf.MaxRetryDelay())
+
+	jconf = &app.JobConfig{MaxRetry: intPtr(3)}
+	assert.Equal(t, time.Duration(2*2*2*2+15+30*3)*time.Second, jconf.MaxRetryDelay())
+
+	jconf = &app.JobConfig{MaxRetry: intPtr(0)}
+	assert.Equal(t, time.Duration(0), jconf.MaxRetryDelay())
+
+	jconf = &app.JobConfig{MaxRetry: intPtr(10), Backoff: app.BackoffExponential, RetryDelaySec: 10, RetryDelayMaxSec: 600}
+	assert.Equal(t, 600*time.Second, jconf.MaxRetryDelay())
+}
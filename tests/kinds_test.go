@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_KindProvider(t *testing.T) {
+	provider := new(mocks.ItemProviderMock)
+
+	//provider with single item type has default kind only
+	same, err := app.NewKindProvider(provider, "")
+	assert.Nil(t, err)
+	assert.Equal(t, provider, same)
+	_, err = app.NewKindProvider(provider, app.DefaultItemKind)
+	assert.Nil(t, err)
+	_, err = app.NewKindProvider(provider, "transaction")
+	assert.NotNil(t, err)
+
+	zil := newZilliqaProvider("http://localhost", 10)
+	assert.Equal(t, []string{"contract", "transaction"}, app.GetProviderItemKinds(zil))
+	txProvider, err := app.NewKindProvider(zil, "Transaction")
+	assert.Nil(t, err)
+	item := txProvider.NewItem("0xABC")
+	assert.Equal(t, "transaction", app.GetItemKind(item))
+	assert.Equal(t, "abc", item.GetId().Id)
+	assert.True(t, item.HasAutosetField("SenderBech32"))
+	assert.Equal(t, app.DefaultItemKind, app.GetItemKind(zil.NewItem("")))
+
+	//unknown kind in config
+	config := zilliqa.ZilliqaConfig{Id: "zilliqa", ChainId: "1", Api: &zilliqa.ZilliqaApiConfig{HttpUrl: "http://localhost"}, ItemKinds: []string{"account"}}
+	assert.NotNil(t, config.Validate())
+}
+
+func Test_FetchTransactionItems(t *testing.T) {
+	stub := &stubNode{deploys: 1, calls: 2, failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+
+	//contracts only by default
+	items, err := provider.FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+
+	//one pass yields both kinds: contract, then deploy, two calls and payment
+	provider.Config.ItemKinds = []string{"contract", "transaction"}
+	requests := stub.getRequests()
+	items, err = provider.FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(items))
+	//block bodies and batch with timestamp, timestamp isn't fetched again for transactions
+	assert.Equal(t, 2, stub.getRequests()-requests)
+	assert.Equal(t, app.DefaultItemKind, app.GetItemKind(items[0]))
+	types := []string{}
+	for _, item := range items[1:] {
+		assert.Equal(t, "transaction", app.GetItemKind(item))
+		types = append(types, item.(*zilliqa.ZilliqaTransaction).Type)
+	}
+	assert.Equal(t, []string{"deploy", "call", "call", "payment"}, types)
+
+	call := items[3].(*zilliqa.ZilliqaTransaction)
+	assert.Equal(t, "ctx1", call.Id)
+	assert.Equal(t, uint(100), call.Block)
+	assert.Equal(t, uint32(1600000000), call.Timestamp)
+	assert.Equal(t, "Transfer", call.Tag)
+	assert.Equal(t, "9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a", call.Sender)
+	assert.Equal(t, stubCalledContract, call.To)
+	assert.False(t, call.Success)
+	assert.Equal(t, uint64(517), call.Gas)
+
+	//block without deploys, timestamp is fetched for transactions
+	provider.Config.ItemKinds = []string{"transaction"}
+	stub.deploys = 0
+	items, err = provider.FetchContainerItems(context.Background(), app.NewItemsContainer([]string{"100"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, uint32(1600000000), items[0].(*zilliqa.ZilliqaTransaction).Timestamp)
+	call.CallAllRealtimeAutosetters(context.Background())
+	assert.Equal(t, "zil1n0lvw9dxh4jcljmzkruvexl69t08zs62ds9ats", call.SenderBech32)
+}
+
+func Test_PropertySetItemKind(t *testing.T) {
+	provider := newZilliqaProvider("http://localhost", 10)
+	tx, err := provider.NewItemOfKind("transaction", "abc")
+	assert.Nil(t, err)
+
+	thejob := job.NewMessageJobItemPropertySet("zilmain", tx, "SenderBech32")
+	assert.Equal(t, "transaction", thejob.ItemKind)
+	assert.Equal(t, "job:property:set:zilmain:transaction:zilliqa_1_abc:SenderBech32", thejob.GetUniqueId())
+	//default kind isn't written into job, so ids of jobs queued before kinds are the same
+	assert.Equal(t, "", job.NewMessageJobItemPropertySet("zilmain", provider.NewItem("abc"), "Name").ItemKind)
+
+	//job restores item of its kind
+	repository := new(mocks.ItemRepositoryMock)
+	repository.On("Get", mock.MatchedBy(func(item app.IItem) bool {
+		return app.GetItemKind(item) == "transaction"
+	})).Return(tx, nil).Once()
+	repository.On("Update", tx, []string{"SenderBech32"}).Return(nil).Once()
+	thejob.SetItemProvider(provider)
+	thejob.SetItemRepository(repository)
+	_, err = thejob.Execute(context.Background())
+	assert.Nil(t, err)
+	repository.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/mongo"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_GapFinder(t *testing.T) {
+	now := time.Now()
+	finder := app.NewGapFinder(10, 30)
+	//worker of container 17 was killed
+	finder.SetProcessingTimeout(now, 30*time.Minute)
+	records := []*app.ContainerRecord{
+		{Seq: 12, Status: app.ContainerStatusDone},
+		{Seq: 13, Status: app.ContainerStatusFailed},
+		{Seq: 14, Status: app.ContainerStatusFailed},
+		{Seq: 15, Status: app.ContainerStatusQueued},
+		{Seq: 16, Status: app.ContainerStatusProcessing, UpdatedAt: now.Add(-time.Minute)},
+		{Seq: 17, Status: app.ContainerStatusProcessing, UpdatedAt: now.Add(-time.Hour)},
+		{Seq: 20, Status: app.ContainerStatusFailed},
+		{Seq: 21, Status: app.ContainerStatusDone},
+	}
+	for _, record := range records {
+		finder.Add(record)
+	}
+
+	gaps := finder.Gaps()
+	assert.Equal(t, []*app.ContainerGap{
+		{From: 10, To: 11, Status: app.ContainerStatusMissing},
+		{From: 13, To: 14, Status: app.ContainerStatusFailed},
+		{From: 17, To: 17, Status: app.ContainerStatusProcessing},
+		{From: 18, To: 19, Status: app.ContainerStatusMissing},
+		{From: 20, To: 20, Status: app.ContainerStatusFailed},
+		{From: 22, To: 30, Status: app.ContainerStatusMissing},
+	}, gaps)
+
+	containers := gaps[5].Containers(3)
+	assert.Equal(t, 3, len(containers))
+	assert.Equal(t, "22", containers[0].String())
+	assert.Equal(t, "24", containers[2].String())
+
+	//nothing is recorded
+	assert.Equal(t, []*app.ContainerGap{{From: 0, To: 5, Status: app.ContainerStatusMissing}}, app.NewGapFinder(0, 5).Gaps())
+}
+
+func Test_ContainerProcessLedger(t *testing.T) {
+	providerKey := "mock"
+	container := app.NewItemsContainer([]string{"100"})
+
+	item := app.NewItem("Mock", "1", "item1")
+	item.RegisterDelayedAutosetter("Delayed", func(ctx context.Context) error { return nil })
+
+	provider := new(mocks.ItemProviderMock)
+	provider.On("FetchContainerItems", container).Return([]app.IItem{item}, nil).Once()
+
+	ledger := new(mocks.ContainerLedgerMock)
+	ledger.On("MarkProcessing", providerKey, container).Return(nil)
+	ledger.On("MarkDone", providerKey, container, 1).Return(nil).Once()
+
+	thejob := job.NewMessageJobContainerProcess(providerKey, container)
+	thejob.SetItemProvider(provider)
+	thejob.SetItemRepository(new(mocks.ItemRepositoryMock))
+	thejob.SetContainerLedger(ledger)
+
+	newJobs, err := thejob.Execute(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(newJobs))
+	ledger.AssertCalled(t, "MarkProcessing", providerKey, container)
+	ledger.AssertCalled(t, "MarkDone", providerKey, container, 1)
+
+	//provider failure is recorded in ledger
+	provider.On("FetchContainerItems", container).Return(nil, errors.New("node is down")).Once()
+	ledger.On("MarkFailed", providerKey, container, mock.Anything).Return(nil).Once()
+
+	newJobs, err = thejob.Execute(context.Background())
+	assert.NotNil(t, err)
+	assert.Nil(t, newJobs)
+	ledger.AssertCalled(t, "MarkFailed", providerKey, container, mock.Anything)
+
+	//failure of attempt which will be retried isn't recorded
+	ledger = new(mocks.ContainerLedgerMock)
+	ledger.On("MarkProcessing", providerKey, container).Return(nil)
+	thejob.SetContainerLedger(ledger)
+	provider.On("FetchContainerItems", container).Return(nil, errors.New("node is down")).Once()
+	_, err = thejob.Execute(app.WithJobAttempt(context.Background(), 0, 3))
+	assert.NotNil(t, err)
+	ledger.AssertNotCalled(t, "MarkFailed", providerKey, container, mock.Anything)
+
+	//the last attempt and permanent error are recorded
+	ledger.On("MarkFailed", providerKey, container, mock.Anything).Return(nil).Twice()
+	provider.On("FetchContainerItems", container).Return(nil, errors.New("node is down")).Once()
+	_, err = thejob.Execute(app.WithJobAttempt(context.Background(), 3, 3))
+	assert.NotNil(t, err)
+	provider.On("FetchContainerItems", container).Return(nil, app.NewClassifiedError(app.ErrorKindPermanent, errors.New("invalid block"))).Once()
+	_, err = thejob.Execute(app.WithJobAttempt(context.Background(), 0, 3))
+	assert.NotNil(t, err)
+	ledger.AssertNumberOfCalls(t, "MarkFailed", 2)
+}
+
+func Test_ContainerLedgerMarkQueued(t *testing.T) {
+	ledger, err := mongo.NewContainerLedger(getTestStorage(t))
+	assert.Nil(t, err)
+	defer ledger.Close()
+	ctx := context.Background()
+	walk := func(seq uint) *app.ContainerRecord {
+		var found *app.ContainerRecord
+		assert.Nil(t, ledger.Walk(ctx, "zilmain", seq, seq, func(record *app.ContainerRecord) error {
+			found = record
+			return nil
+		}))
+		return found
+	}
+
+	//worker took the job first, its status stays
+	done := app.NewItemsContainer([]string{"1"})
+	queuedAt := time.Now()
+	assert.Nil(t, ledger.MarkProcessing(ctx, "zilmain", done))
+	assert.Nil(t, ledger.MarkDone(ctx, "zilmain", done, 3))
+	assert.Nil(t, ledger.MarkQueued(ctx, "zilmain", done, queuedAt))
+	assert.Equal(t, app.ContainerStatusDone, walk(1).Status)
+
+	//failed container queued again by backfill
+	failed := app.NewItemsContainer([]string{"2"})
+	assert.Nil(t, ledger.MarkFailed(ctx, "zilmain", failed, errors.New("node error")))
+	assert.Nil(t, ledger.MarkQueued(ctx, "zilmain", failed, time.Now()))
+	assert.Equal(t, app.ContainerStatusQueued, walk(2).Status)
+
+	//new job failed before it's marked as queued
+	queuedAt = time.Now()
+	assert.Nil(t, ledger.MarkFailed(ctx, "zilmain", failed, errors.New("node error")))
+	assert.Nil(t, ledger.MarkQueued(ctx, "zilmain", failed, queuedAt))
+	assert.Equal(t, app.ContainerStatusFailed, walk(2).Status)
+
+	assert.Nil(t, ledger.MarkQueued(ctx, "zilmain", app.NewItemsContainer([]string{"3"}), time.Now()))
+	assert.Equal(t, app.ContainerStatusQueued, walk(3).Status)
+}
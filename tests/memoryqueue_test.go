@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	factory_pkg "purrproof/smartcrawl/factory"
+	"purrproof/smartcrawl/memory"
+	"purrproof/smartcrawl/mocks"
+	"sync"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_MemoryQueueCrawl(t *testing.T) {
+	providerKey := "mock"
+	container := app.NewItemsContainer([]string{"100"})
+
+	appConfig, err := app.NewConfig("..")
+	assert.Nil(t, err)
+	factory := factory_pkg.NewFactory(appConfig)
+
+	//delayed property is set by child job
+	item := app.NewItem("Mock", "1", "item1")
+	delayedCalls := 0
+	item.RegisterDelayedAutosetter("Delayed", func(ctx context.Context) error {
+		delayedCalls++
+		return nil
+	})
+
+	provider := new(mocks.ItemProviderMock)
+	provider.On("FetchContainerItems", container).Return([]app.IItem{item}, nil).Once()
+	factory.ItemProvider[providerKey] = provider
+
+	repository := new(mocks.ItemRepositoryMock)
+	repository.On("Get", mock.Anything).Return(item, nil).Once()
+	repository.On("Update", item, []string{"Delayed"}).Return(nil).Once()
+	factory.ItemRepository = repository
+
+	ledger := new(mocks.ContainerLedgerMock)
+	ledger.On("MarkProcessing", providerKey, container).Return(nil).Once()
+	ledger.On("MarkDone", providerKey, container, 1).Return(nil).Once()
+	factory.Ledger = ledger
+	factory.SnapshotStore = new(mocks.SnapshotStoreMock)
+
+	jobQueue, err := factory.NewMemoryJobQueue()
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	info, err := jobQueue.Add(job.NewMessageJobContainerProcess(providerKey, container))
+	assert.Nil(t, err)
+	assert.False(t, info.Duplicate)
+
+	//same container isn't queued twice
+	info, err = jobQueue.Add(job.NewMessageJobContainerProcess(providerKey, container))
+	assert.Nil(t, err)
+	assert.True(t, info.Duplicate)
+	depth, _ := jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Equal(t, 1, depth)
+
+	err = jobQueue.Process(job.JobTypeContainerProcess+","+job.JobTypePropertySet+":*", 2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, delayedCalls)
+	provider.AssertExpectations(t)
+	repository.AssertExpectations(t)
+	ledger.AssertExpectations(t)
+	depth, _ = jobQueue.GetQueueDepth(job.JobTypePropertySet + ":Delayed")
+	assert.Equal(t, 0, depth)
+
+	//processed job may be queued again
+	info, err = jobQueue.Add(job.NewMessageJobContainerProcess(providerKey, container))
+	assert.Nil(t, err)
+	assert.False(t, info.Duplicate)
+}
+
+func Test_MemoryQueueRetry(t *testing.T) {
+	conf := &app.QueueConfig{
+		Job: &app.JobConfig{
+			MaxRetry:      intPtr(1),
+			Backoff:       app.BackoffFixed,
+			RetryDelaySec: 1,
+		},
+	}
+
+	//container "1" fails once, container "2" fails always
+	var mu sync.Mutex
+	attempts := make(map[string]int, 0)
+	handler := func(ctx context.Context, payload []byte) ([]app.IJob, error) {
+		thejob := &job.JobContainerProcess{}
+		err := json.Unmarshal(payload, thejob)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		key := thejob.Container.String()
+		attempts[key]++
+		if key == "2" || attempts[key] == 1 {
+			return nil, errors.New("node is down")
+		}
+		return nil, nil
+	}
+
+	jobQueue, err := memory.NewJobQueue(conf, handler)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+
+	for _, id := range []string{"1", "2"} {
+		_, err := jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{id})))
+		assert.Nil(t, err)
+	}
+
+	err = jobQueue.Process(job.JobTypeContainerProcess, 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, attempts["1"])
+	//first attempt + MaxRetry
+	assert.Equal(t, 2, attempts["2"])
+	depth, _ := jobQueue.GetQueueDepth(job.JobTypeContainerProcess)
+	assert.Equal(t, 0, depth)
+}
+
+func Test_MemoryQueueNoRetry(t *testing.T) {
+	conf := &app.QueueConfig{
+		Job: &app.JobConfig{MaxRetry: intPtr(0)},
+	}
+	attempts := 0
+	lastAttempt := false
+	handler := func(ctx context.Context, payload []byte) ([]app.IJob, error) {
+		attempts++
+		lastAttempt = app.IsLastJobAttempt(ctx)
+		return nil, errors.New("node is down")
+	}
+
+	jobQueue, err := memory.NewJobQueue(conf, handler)
+	assert.Nil(t, err)
+	defer jobQueue.Close()
+	_, err = jobQueue.Add(job.NewMessageJobContainerProcess("mock", app.NewItemsContainer([]string{"1"})))
+	assert.Nil(t, err)
+
+	err = jobQueue.Process(job.JobTypeContainerProcess, 1)
+	assert.NotNil(t, err)
+	//the first attempt is the last one
+	assert.Equal(t, 1, attempts)
+	assert.True(t, lastAttempt)
+}
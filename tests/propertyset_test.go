@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"purrproof/smartcrawl/app"
@@ -43,6 +44,7 @@ func Test_PropertySet(t *testing.T) {
 	//init repository
 	repoMock := new(mocks.ItemRepositoryMock)
 	factory.ItemRepository = repoMock
+	factory.SnapshotStore = new(mocks.SnapshotStoreMock)
 
 	someItem := provider.NewItem(iid)
 	repoMock.On("Get", mock.AnythingOfType("*zilliqa.ZilliqaContract")).Return(someItem, nil).Once()
@@ -63,8 +65,10 @@ func Test_PropertySet(t *testing.T) {
 	restoredJob, err := factory.UnmarshalJob(payload)
 	assert.Nil(t, err)
 	assert.Equal(t, "*job.JobPropertySet", reflect.TypeOf(restoredJob).String())
+	//task id doesn't depend on job instance
+	assert.Equal(t, thejob.GetUniqueId(), restoredJob.GetUniqueId())
 
-	newJobs, err := restoredJob.Execute()
+	newJobs, err := restoredJob.Execute(context.Background())
 	repoMock.AssertCalled(t, "Get", mock.AnythingOfType("*zilliqa.ZilliqaContract"))
 	repoMock.AssertCalled(t, "Update", mock.AnythingOfType("*zilliqa.ZilliqaContract"), []string{propertyName})
 	assert.Nil(t, err)
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/zilliqa"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	factory_pkg "purrproof/smartcrawl/factory"
+)
+
+func Test_ProviderRegistry(t *testing.T) {
+	appConfig, err := app.NewConfig("..")
+	assert.Nil(t, err)
+	factory := factory_pkg.NewFactory(appConfig)
+
+	//two entries of the same type coexist
+	mainnet, err := factory.GetProviderByKey("zilmain")
+	assert.Nil(t, err)
+	testnet, err := factory.GetProviderByKey("zildev")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", mainnet.(*zilliqa.ZilliqaBlockchain).Config.ChainId)
+	assert.Equal(t, "333", testnet.(*zilliqa.ZilliqaBlockchain).Config.ChainId)
+
+	evmProvider, err := factory.GetProviderByKey("ethmain")
+	assert.Nil(t, err)
+	assert.Equal(t, "*evm.EvmBlockchain", reflect.TypeOf(evmProvider).String())
+
+	_, err = factory.GetProviderByKey("unknown")
+	assert.NotNil(t, err)
+
+	assert.Contains(t, app.GetItemProviderTypes(), "zilliqa")
+	assert.Contains(t, app.GetItemProviderTypes(), "evm")
+}
+
+func Test_ProviderRegistryInvalidConfig(t *testing.T) {
+	appConfig := &app.AppConfig{
+		Providers: map[string]*app.ItemProviderConfig{
+			"notype":      {"id": "Zilliqa"},
+			"unknowntype": {"type": "bitcoin"},
+			"noid":        {"type": "zilliqa", "chainid": "1", "api": map[string]interface{}{"httpurl": "http://localhost"}},
+			"badapi":      {"type": "zilliqa", "id": "Zilliqa", "chainid": "1", "api": "http://localhost"},
+		},
+	}
+	factory := factory_pkg.NewFactory(appConfig)
+
+	_, err := factory.GetProviderByKey("notype")
+	assert.ErrorContains(t, err, "provider type is not defined")
+
+	_, err = factory.GetProviderByKey("unknowntype")
+	assert.ErrorContains(t, err, "unknown provider type: bitcoin")
+
+	_, err = factory.GetProviderByKey("noid")
+	assert.ErrorContains(t, err, "Id is not defined")
+
+	_, err = factory.GetProviderByKey("badapi")
+	assert.ErrorContains(t, err, "can't decode provider config")
+}
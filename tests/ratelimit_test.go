@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/memory"
+	"purrproof/smartcrawl/redis"
+	"purrproof/smartcrawl/zilliqa"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+
+	factory_pkg "purrproof/smartcrawl/factory"
+)
+
+func Test_RateLimitConfig(t *testing.T) {
+	pconf := &app.ItemProviderConfig{"ratelimit": map[string]interface{}{"requestspersec": "2.5"}}
+	rconf, err := pconf.GetRateLimitConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, rconf.RequestsPerSec)
+	assert.Equal(t, 3, rconf.Burst)
+
+	rconf, err = (&app.ItemProviderConfig{}).GetRateLimitConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, rconf)
+
+	_, err = (&app.ItemProviderConfig{"ratelimit": map[string]interface{}{"burst": 5}}).GetRateLimitConfig()
+	assert.NotNil(t, err)
+}
+
+func Test_MemoryRateLimiter(t *testing.T) {
+	limiter := memory.NewRateLimiter(&app.RateLimitConfig{RequestsPerSec: 10, Burst: 2})
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, limiter.Wait(context.Background()))
+	}
+	//burst of 2, then 3 requests at 10 rps
+	assert.GreaterOrEqual(t, time.Since(started), 250*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := limiter.Wait(ctx)
+	assert.Equal(t, app.ErrorKindTransient, app.GetErrorKind(err))
+}
+
+func Test_ProviderRateLimit(t *testing.T) {
+	var calls int32
+	node := newCountingNode(&calls, http.StatusOK)
+	defer node.Close()
+
+	appConfig := &app.AppConfig{
+		Providers: map[string]*app.ItemProviderConfig{
+			"zillimited": {
+				"type":      "zilliqa",
+				"id":        "Zilliqa",
+				"chainid":   "1",
+				"api":       map[string]interface{}{"httpurl": node.URL},
+				"ratelimit": map[string]interface{}{"requestspersec": 20, "burst": 1},
+			},
+		},
+	}
+	factory := factory_pkg.NewFactory(appConfig)
+	defer factory.RunDeferred()
+	provider, err := factory.GetProviderByKey("zillimited")
+	assert.Nil(t, err)
+
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := provider.(*zilliqa.ZilliqaBlockchain).GetLatestBlockId(context.Background())
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+	assert.GreaterOrEqual(t, time.Since(started), 200*time.Millisecond)
+}
+
+// commands processing jobs in memory queue don't use Redis of queue config for rate limit
+func Test_ProviderRateLimitLocalJobs(t *testing.T) {
+	var calls int32
+	node := newCountingNode(&calls, http.StatusOK)
+	defer node.Close()
+
+	appConfig := &app.AppConfig{
+		//nothing listens there
+		Queue: &app.QueueConfig{Addr: "127.0.0.1:1"},
+		Providers: map[string]*app.ItemProviderConfig{
+			"zillimited": {
+				"type":      "zilliqa",
+				"id":        "Zilliqa",
+				"chainid":   "1",
+				"api":       map[string]interface{}{"httpurl": node.URL},
+				"ratelimit": map[string]interface{}{"requestspersec": 20, "burst": 1},
+			},
+		},
+	}
+	factory := factory_pkg.NewFactory(appConfig)
+	factory.LocalJobs = true
+	defer factory.RunDeferred()
+	provider, err := factory.GetProviderByKey("zillimited")
+	assert.Nil(t, err)
+	_, err = provider.(*zilliqa.ZilliqaBlockchain).GetLatestBlockId(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	//workers share the limit through Redis
+	factory = factory_pkg.NewFactory(appConfig)
+	defer factory.RunDeferred()
+	provider, err = factory.GetProviderByKey("zillimited")
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = provider.(*zilliqa.ZilliqaBlockchain).GetLatestBlockId(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// limit is shared by limiters with the same name, like by workers on different machines
+func Test_RedisRateLimiter(t *testing.T) {
+	qconf := &app.QueueConfig{Addr: getTestRedisAddr(t)}
+	rconf := &app.RateLimitConfig{RequestsPerSec: 10, Burst: 1}
+	name := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	limiter1 := redis.NewRateLimiter(qconf, name, rconf)
+	defer limiter1.Close()
+	limiter2 := redis.NewRateLimiter(qconf, name, rconf)
+	defer limiter2.Close()
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, limiter1.Wait(context.Background()))
+		assert.Nil(t, limiter2.Wait(context.Background()))
+	}
+	//6 requests at 10 rps, the first one is burst
+	assert.GreaterOrEqual(t, time.Since(started), 450*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := limiter1.Wait(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+}
+
+// redis address from REDIS_ADDR, test is skipped if redis isn't running
+func getTestRedisAddr(t *testing.T) string {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	conn, err := net.DialTimeout("tcp", addr, 200*time.Millisecond)
+	if err != nil {
+		t.Skip("redis is not available: ", addr)
+	}
+	conn.Close()
+	return addr
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
+	"purrproof/smartcrawl/zilliqa"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RetryDelay(t *testing.T) {
+	policy := helpers.NewRetryPolicy(&helpers.RetryConfig{DelayMs: 100, MaxDelayMs: 1000, Jitter: 0.5}, nil)
+	for i := 0; i < 20; i++ {
+		delay := policy.Delay(1)
+		assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		assert.LessOrEqual(t, delay, 100*time.Millisecond)
+
+		delay = policy.Delay(3)
+		assert.GreaterOrEqual(t, delay, 200*time.Millisecond)
+		assert.LessOrEqual(t, delay, 400*time.Millisecond)
+
+		//capped
+		delay = policy.Delay(10)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1000*time.Millisecond)
+	}
+}
+
+func Test_RetryAttempts(t *testing.T) {
+	policy := helpers.NewRetryPolicy(&helpers.RetryConfig{MaxAttempts: 5, DelayMs: 1}, nil)
+
+	attempts := 0
+	err := policy.Do(context.Background(), nil, func(ctx context.Context) error {
+		attempts++
+		return &jsonrpc.HTTPError{StatusCode: 502}
+	})
+	assert.Equal(t, 5, attempts)
+	assert.Equal(t, app.ErrorKindTransient, app.GetErrorKind(err))
+
+	//permanent error isn't retried
+	attempts = 0
+	err = policy.Do(context.Background(), nil, func(ctx context.Context) error {
+		attempts++
+		return &jsonrpc.HTTPError{StatusCode: 400}
+	})
+	assert.Equal(t, 1, attempts)
+	assert.True(t, app.IsPermanentError(err))
+
+	attempts = 0
+	err = policy.Do(context.Background(), nil, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return &jsonrpc.HTTPError{StatusCode: 503}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+// retry which doesn't fit into deadline isn't started, job retry takes over
+func Test_RetryDeadline(t *testing.T) {
+	policy := helpers.NewRetryPolicy(&helpers.RetryConfig{MaxAttempts: 5, DelayMs: 1000}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	started := time.Now()
+	err := policy.Do(ctx, nil, func(ctx context.Context) error {
+		attempts++
+		return &jsonrpc.HTTPError{StatusCode: 502}
+	})
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+	assert.Equal(t, app.ErrorKindTransient, app.GetErrorKind(err))
+}
+
+func Test_RetryAfter(t *testing.T) {
+	var calls int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "jsonrpc": "2.0", "result": "10"})
+	}))
+	defer node.Close()
+
+	provider, err := zilliqa.NewZilliqaBlockchain(&zilliqa.ZilliqaConfig{
+		Id:      "zilliqa",
+		ChainId: "1",
+		Api: &zilliqa.ZilliqaApiConfig{
+			HttpUrl: node.URL,
+			Retry:   &helpers.RetryConfig{DelayMs: 10},
+		},
+	})
+	assert.Nil(t, err)
+
+	started := time.Now()
+	blockId, err := provider.GetLatestBlockId(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint(9), blockId)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.GreaterOrEqual(t, time.Since(started), time.Second)
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+	breaker := helpers.NewCircuitBreaker("node", &helpers.CircuitBreakerConfig{FailureThreshold: 3, OpenSec: 1})
+
+	breaker.Failure(0)
+	breaker.Failure(0)
+	assert.Equal(t, time.Duration(0), breaker.OpenFor())
+	breaker.Failure(0)
+	assert.Greater(t, breaker.OpenFor(), time.Duration(0))
+
+	//breaker closes after OpenSec
+	time.Sleep(breaker.OpenFor())
+	assert.Equal(t, time.Duration(0), breaker.OpenFor())
+
+	//half-open: the next failure opens breaker again, success closes it
+	breaker.Failure(0)
+	assert.Greater(t, breaker.OpenFor(), time.Duration(0))
+	breaker.Success()
+	breaker.Failure(0)
+	breaker.Failure(0)
+	assert.Greater(t, breaker.OpenFor(), time.Duration(0))
+
+	//pause requested by node
+	breaker2 := helpers.NewCircuitBreaker("node2", &helpers.CircuitBreakerConfig{FailureThreshold: -1})
+	for i := 0; i < 20; i++ {
+		breaker2.Failure(0)
+	}
+	assert.Equal(t, time.Duration(0), breaker2.OpenFor())
+	breaker2.Failure(2 * time.Second)
+	assert.Greater(t, breaker2.OpenFor(), time.Second)
+}
+
+func Test_SharedCircuitBreaker(t *testing.T) {
+	url := "http://shared-breaker.local"
+	conf := &helpers.CircuitBreakerConfig{FailureThreshold: 2, OpenSec: 60}
+
+	//the same endpoint and settings share breaker, defaults are the same as unset settings
+	breaker := helpers.GetCircuitBreaker(url, conf)
+	assert.True(t, breaker == helpers.GetCircuitBreaker(url, &helpers.CircuitBreakerConfig{FailureThreshold: 2, OpenSec: 60}))
+	assert.True(t, helpers.GetCircuitBreaker(url, nil) == helpers.GetCircuitBreaker(url, &helpers.CircuitBreakerConfig{}))
+
+	//other settings aren't overridden by settings of breaker created first
+	other := helpers.GetCircuitBreaker(url, &helpers.CircuitBreakerConfig{FailureThreshold: 1, OpenSec: 60})
+	assert.False(t, breaker == other)
+	other.Failure(0)
+	assert.Greater(t, other.OpenFor(), time.Duration(0))
+	assert.Equal(t, time.Duration(0), breaker.OpenFor())
+}
+
+func Test_CircuitBreakerStopsCalls(t *testing.T) {
+	var calls int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer node.Close()
+
+	endpoints := []helpers.EndpointConfig{{HttpUrl: node.URL}}
+	pool, err := helpers.NewEndpointPool("node", endpoints, time.Second, &helpers.CircuitBreakerConfig{FailureThreshold: 2, OpenSec: 60}, nil)
+	assert.Nil(t, err)
+	policy := helpers.NewRetryPolicy(&helpers.RetryConfig{MaxAttempts: 10, DelayMs: 1}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = policy.Do(ctx, nil, func(ctx context.Context) error {
+		return pool.Do(ctx, func(ctx context.Context, client *jsonrpc.Client) error {
+			return client.Call(ctx, "GetNumTxBlocks", nil)
+		})
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
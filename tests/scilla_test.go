@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"purrproof/smartcrawl/zilliqa"
+	"purrproof/smartcrawl/zilliqa/scilla"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestContract(t *testing.T, name string) string {
+	code, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(code)
+}
+
+func Test_ScillaParse(t *testing.T) {
+	contract, err := scilla.Parse(readTestContract(t, "fungible_token.scilla"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, contract.ScillaVersion)
+	assert.Equal(t, []string{"BoolUtils", "IntUtils", "ListUtils"}, contract.Imports)
+	assert.Equal(t, "FungibleToken", contract.Library)
+	assert.Equal(t, "FungibleToken", contract.Name)
+	assert.Equal(t, []scilla.Param{
+		{Name: "contract_owner", Type: "ByStr20"},
+		{Name: "name", Type: "String"},
+		{Name: "symbol", Type: "String"},
+		{Name: "decimals", Type: "Uint32"},
+		{Name: "init_supply", Type: "Uint128"},
+		{Name: "registry", Type: "ByStr20 with contract field allowed: Map ByStr20 Bool, field admin: ByStr20 end"},
+	}, contract.Params)
+	assert.Equal(t, []scilla.Param{
+		{Name: "total_supply", Type: "Uint128"},
+		{Name: "balances", Type: "Map ByStr20 Uint128"},
+		{Name: "allowances", Type: "Map ByStr20 (Map ByStr20 Uint128)"},
+	}, contract.Fields)
+	assert.Equal(t, []scilla.Component{
+		{Name: "ThrowError", Params: []scilla.Param{{Name: "err", Type: "Error"}}},
+		{Name: "IsNotSender", Params: []scilla.Param{{Name: "address", Type: "ByStr20"}}},
+	}, contract.Procedures)
+	assert.Equal(t, []scilla.Component{
+		{Name: "IncreaseAllowance", Params: []scilla.Param{{Name: "spender", Type: "ByStr20"}, {Name: "amount", Type: "Uint128"}}},
+		{Name: "Transfer", Params: []scilla.Param{{Name: "to", Type: "ByStr20"}, {Name: "amount", Type: "Uint128"}}},
+		{Name: "Burn", Params: []scilla.Param{}},
+	}, contract.Transitions)
+	assert.Equal(t, []string{"IncreasedAllowance", "TransferSuccess", "Burnt"}, contract.Events)
+	assert.True(t, contract.HasTransition("Transfer"))
+	assert.False(t, contract.HasTransition("Fake"))
+}
+
+func Test_ScillaParseMalformed(t *testing.T) {
+	contract, err := scilla.Parse("scilla_version 1\nlibrary Lib\ncontract Broken(owner: ByStr20)\nfield f : Uint32 = Uint32 0\n(* unterminated")
+	assert.ErrorContains(t, err, "unterminated comment")
+	assert.Equal(t, 1, contract.ScillaVersion)
+	assert.Equal(t, "Broken", contract.Name)
+	assert.Equal(t, 1, len(contract.Fields))
+
+	_, err = scilla.Parse("scilla_version 0")
+	assert.ErrorContains(t, err, "contract declaration not found")
+}
+
+func Test_ScillaParseLibraryAddressType(t *testing.T) {
+	code := `scilla_version 0
+library Registry
+let get_admin =
+  fun (r : ByStr20 with contract field admin : ByStr20 end) =>
+  r
+contract Registry(owner: ByStr20)
+field admin : ByStr20 = owner
+transition SetAdmin(to: ByStr20)
+end`
+	contract, err := scilla.Parse(code)
+	assert.Nil(t, err)
+	assert.Equal(t, "Registry", contract.Library)
+	assert.Equal(t, "Registry", contract.Name)
+	assert.Equal(t, []scilla.Param{{Name: "owner", Type: "ByStr20"}}, contract.Params)
+	assert.Equal(t, []scilla.Param{{Name: "admin", Type: "ByStr20"}}, contract.Fields)
+	assert.True(t, contract.HasTransition("SetAdmin"))
+}
+
+func Test_ContractParsedProperties(t *testing.T) {
+	contract := newZilliqaProvider("http://localhost:4201", 0).NewItem("0x4baf5fada8e5db92c3d3242618c5b47133ae003c").(*zilliqa.ZilliqaContract)
+	contract.Code = readTestContract(t, "fungible_token.scilla")
+	contract.CallAllRealtimeAutosetters(context.Background())
+	assert.Equal(t, "FungibleToken", contract.Name)
+	assert.Equal(t, "FungibleToken", contract.Library)
+	assert.Equal(t, 3, len(contract.Imports))
+	assert.Equal(t, 6, len(contract.Params))
+	assert.Equal(t, 3, len(contract.Fields))
+	assert.Equal(t, 3, len(contract.Transitions))
+	assert.Equal(t, 2, len(contract.Procedures))
+	assert.Equal(t, 3, len(contract.Events))
+
+	//code is parsed again when it's changed
+	contract.Code = "contract Empty()"
+	err := contract.CallAutosetter(context.Background(), "Transitions")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(contract.Transitions))
+
+	//malformed code keeps partial values, it isn't an error of property
+	contract.Code = "scilla_version 0\ncontract"
+	err = contract.CallAutosetter(context.Background(), "ScillaVersion")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, contract.ScillaVersion)
+	_, err = contract.Parse()
+	assert.NotNil(t, err)
+}
+
+func Test_ContractCodeHash(t *testing.T) {
+	provider := newZilliqaProvider("http://localhost:4201", 0)
+	hash := func(code string) string {
+		contract := provider.NewItem("0x4baf5fada8e5db92c3d3242618c5b47133ae003c").(*zilliqa.ZilliqaContract)
+		contract.Code = code
+		assert.Nil(t, contract.CallAutosetter(context.Background(), "CodeHash"))
+		return contract.CodeHash
+	}
+	original := hash("scilla_version 0\ncontract Clone(owner: ByStr20)\nfield msg : String = \"hello  world\"")
+	assert.Equal(t, 66, len(original))
+	//formatting and comments don't matter
+	assert.Equal(t, original, hash("(* redeploy *)\nscilla_version   0\r\n\ncontract Clone (owner : ByStr20) (* owner (* nested *) *)\n  field msg: String = \"hello  world\"\n"))
+	//string literals do
+	assert.NotEqual(t, original, hash("scilla_version 0\ncontract Clone(owner: ByStr20)\nfield msg : String = \"hello world\""))
+
+	normalized, err := scilla.Normalize("transition T() (* c *)\n  e = {_eventname : \"E\"};\nend")
+	assert.Nil(t, err)
+	assert.Equal(t, "transition T ( ) e = { _eventname : \"E\" } ; end", normalized)
+}
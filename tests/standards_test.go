@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"purrproof/smartcrawl/zilliqa"
+	"purrproof/smartcrawl/zilliqa/scilla"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const nftContract = `scilla_version 0
+library NFT
+contract NFT(initial_contract_owner: ByStr20, initial_base_uri: String, name: String, symbol: String)
+field token_owners: Map Uint256 ByStr20 = Emp Uint256 ByStr20
+field balances: Map ByStr20 Uint128 = Emp ByStr20 Uint128
+field spenders: Map Uint256 ByStr20 = Emp Uint256 ByStr20
+field operators: Map ByStr20 (Map ByStr20 Bool) = Emp ByStr20 (Map ByStr20 Bool)
+transition SetSpender(spender: ByStr20, token_id: Uint256)
+  e = {_eventname: "SetSpender"}; event e
+end
+transition AddOperator(operator: ByStr20)
+  e = {_eventname: "AddOperator"}; event e
+end
+transition RemoveOperator(operator: ByStr20)
+  e = {_eventname: "RemoveOperator"}; event e
+end
+transition TransferFrom(to: ByStr20, token_id: Uint256)
+  e = {_eventname: "TransferFrom"}; event e
+end
+`
+
+func Test_DetectStandards(t *testing.T) {
+	nft, err := scilla.Parse(nftContract)
+	assert.Nil(t, err)
+	assert.Equal(t, []zilliqa.StandardConformance{
+		{Standard: "ZRC-6", Conformant: true, Missing: []string{}},
+	}, zilliqa.DetectStandards(nft))
+
+	//fungible token without TransferFrom and DecreaseAllowance
+	token, err := scilla.Parse(readTestContract(t, "fungible_token.scilla"))
+	assert.Nil(t, err)
+	assert.Equal(t, []zilliqa.StandardConformance{
+		{Standard: "ZRC-2", Conformant: false, Missing: []string{
+			"transition DecreaseAllowance(spender, amount)",
+			"transition TransferFrom(from, to, amount)",
+			"event DecreasedAllowance",
+			"event TransferFromSuccess",
+		}},
+	}, zilliqa.DetectStandards(token))
+
+	//reference contract of ZRC-2, reference-contracts/FungibleToken.scilla of https://github.com/Zilliqa/ZRC
+	reference, err := scilla.Parse(readTestContract(t, "zrc2_fungible_token.scilla"))
+	assert.Nil(t, err)
+	assert.Equal(t, []zilliqa.StandardConformance{
+		{Standard: "ZRC-2", Conformant: true, Missing: []string{}},
+	}, zilliqa.DetectStandards(reference))
+
+	empty, _ := scilla.Parse("contract Empty()")
+	assert.Equal(t, 0, len(zilliqa.DetectStandards(empty)))
+}
+
+func Test_ContractStandards(t *testing.T) {
+	contract := newZilliqaProvider("http://localhost:4201", 0).NewItem("0x4baf5fada8e5db92c3d3242618c5b47133ae003c").(*zilliqa.ZilliqaContract)
+	contract.Code = nftContract
+	err := contract.CallAutosetter(context.Background(), "Standards")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(contract.Standards))
+	assert.Equal(t, "ZRC-6", contract.Standards[0].Standard)
+	assert.True(t, contract.Standards[0].Conformant)
+}
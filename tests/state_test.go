@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/mongo"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const stateContract = "0x4baf5fada8e5db92c3d3242618c5b47133ae003c"
+
+// snapshots saved by contract, in order of saving
+func newSnapshotStore(keep int) (*mocks.SnapshotStoreMock, *[]*zilliqa.StateSnapshot) {
+	snapshots := make([]*zilliqa.StateSnapshot, 0)
+	store := new(mocks.SnapshotStoreMock)
+	store.On("SaveSnapshot", mock.MatchedBy(func(snapshot *app.PropertySnapshot) bool {
+		return snapshot.Property == "State"
+	})).Run(func(args mock.Arguments) {
+		snapshots = append(snapshots, args.Get(0).(*app.PropertySnapshot).Value.(*zilliqa.StateSnapshot))
+	}).Return(nil)
+	store.On("PruneSnapshots", mock.Anything, "State", keep).Return(nil)
+	return store, &snapshots
+}
+
+func Test_ContractStateSnapshot(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+	provider.Config.State = &zilliqa.ZilliqaStateConfig{HistorySize: 2}
+
+	contract := provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	assert.True(t, contract.HasAutosetField("State"))
+	assert.Contains(t, contract.GetDelayedAutosetters(), "State")
+	//state isn't fetched if there is no store to save it
+	assert.NotNil(t, contract.CallAutosetter(context.Background(), "State"))
+
+	//the latest snapshot and 2 previous ones are kept
+	store, snapshots := newSnapshotStore(3)
+	contract.SetSnapshotStore(store)
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, contract.CallAutosetter(context.Background(), "State"))
+	}
+	store.AssertNumberOfCalls(t, "PruneSnapshots", 4)
+	assert.Equal(t, 4, len(*snapshots))
+	latest := (*snapshots)[3]
+	assert.Equal(t, uint(100), latest.Height)
+	assert.Equal(t, "4000", latest.Fields["total_supply"])
+	assert.Equal(t, map[string]interface{}{"0x4baf5fada8e5db92c3d3242618c5b47133ae003c": "100"}, latest.Fields["balances"])
+
+	//item keeps summary of the latest snapshot only
+	assert.Equal(t, uint(100), contract.State.Height)
+	assert.Equal(t, latest.TakenAt, contract.State.TakenAt)
+	assert.Equal(t, []string{"_balance", "balances", "total_supply"}, contract.State.FieldNames)
+	data, err := bson.Marshal(contract)
+	assert.Nil(t, err)
+	var doc bson.M
+	assert.Nil(t, bson.Unmarshal(data, &doc))
+	state := doc["state"].(bson.M)
+	assert.Equal(t, int64(100), state["height"])
+	assert.Contains(t, state, app.SnapshotTimeField)
+	assert.NotContains(t, state, "fields")
+	assert.NotContains(t, state, "history")
+}
+
+func Test_ContractStateFields(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+	provider.Config.State = &zilliqa.ZilliqaStateConfig{Fields: []string{"balances", "missing"}, HistorySize: -1}
+
+	//history is disabled, the latest snapshot is kept only
+	store, snapshots := newSnapshotStore(1)
+	contract := provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	contract.SetSnapshotStore(store)
+	assert.Nil(t, contract.CallAutosetter(context.Background(), "State"))
+	assert.Equal(t, 1, len((*snapshots)[0].Fields))
+	assert.Contains(t, (*snapshots)[0].Fields, "balances")
+	assert.Equal(t, []string{"balances"}, contract.State.FieldNames)
+	store.AssertExpectations(t)
+}
+
+func Test_ContractStateMaxSize(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+
+	//whole state: small fields fit, balances map is skipped
+	provider := newZilliqaProvider(node.URL, 10)
+	provider.Config.State = &zilliqa.ZilliqaStateConfig{HistorySize: -1, MaxSize: 20}
+	store, snapshots := newSnapshotStore(1)
+	contract := provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	contract.SetSnapshotStore(store)
+	assert.Nil(t, contract.CallAutosetter(context.Background(), "State"))
+	assert.Equal(t, []string{"_balance", "total_supply"}, contract.State.FieldNames)
+	assert.Equal(t, []string{"balances"}, contract.State.SkippedFields)
+	assert.Equal(t, []string{"balances"}, (*snapshots)[0].SkippedFields)
+	assert.NotContains(t, (*snapshots)[0].Fields, "balances")
+
+	//configured fields are limited the same way
+	provider = newZilliqaProvider(node.URL, 10)
+	provider.Config.State = &zilliqa.ZilliqaStateConfig{Fields: []string{"balances", "total_supply"}, HistorySize: -1, MaxSize: 20}
+	store, snapshots = newSnapshotStore(1)
+	contract = provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	contract.SetSnapshotStore(store)
+	assert.Nil(t, contract.CallAutosetter(context.Background(), "State"))
+	assert.Equal(t, []string{"total_supply"}, contract.State.FieldNames)
+	assert.Equal(t, []string{"balances"}, contract.State.SkippedFields)
+	assert.Equal(t, "1000", (*snapshots)[0].Fields["total_supply"])
+
+	//default limit keeps the whole state of small contract
+	provider = newZilliqaProvider(node.URL, 10)
+	provider.Config.State = &zilliqa.ZilliqaStateConfig{HistorySize: -1}
+	store, _ = newSnapshotStore(1)
+	contract = provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	contract.SetSnapshotStore(store)
+	assert.Nil(t, contract.CallAutosetter(context.Background(), "State"))
+	assert.Equal(t, 3, len(contract.State.FieldNames))
+	assert.Empty(t, contract.State.SkippedFields)
+}
+
+func Test_ContractStateJob(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+
+	stored := provider.NewItem(stateContract)
+	repository := &mocks.ItemRepositoryMock{}
+	repository.On("Get", mock.Anything).Return(stored, nil)
+	repository.On("Update", stored, []string{"State"}).Return(nil)
+
+	jobmsg := job.NewMessageJobPropertySet("zilmain", stored.GetId(), "State")
+	jobmsg.SetItemProvider(provider)
+	jobmsg.SetItemRepository(repository)
+	store, snapshots := newSnapshotStore(11)
+	jobmsg.SetSnapshotStore(store)
+	_, err := jobmsg.Execute(context.Background())
+	assert.Nil(t, err)
+	repository.AssertExpectations(t)
+	store.AssertExpectations(t)
+	assert.Equal(t, "1000", (*snapshots)[0].Fields["total_supply"])
+	assert.Equal(t, uint(100), stored.(*zilliqa.ZilliqaContract).State.Height)
+}
+
+func Test_SnapshotStore(t *testing.T) {
+	storage := getTestStorage(t)
+	store, err := mongo.NewSnapshotStore(storage)
+	assert.Nil(t, err)
+	defer store.Close()
+	ctx := context.Background()
+
+	id := &app.ItemId{ProvName: "zilliqa", ProvBranch: "1", Id: "4baf5fada8e5db92c3d3242618c5b47133ae003c"}
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	for i := 0; i < 4; i++ {
+		snapshot := &app.PropertySnapshot{Item: id, Property: "State", TakenAt: start.Add(time.Duration(i) * time.Minute), Value: bson.M{"total_supply": i}}
+		assert.Nil(t, store.SaveSnapshot(ctx, snapshot))
+		//saving again does nothing
+		assert.Nil(t, store.SaveSnapshot(ctx, snapshot))
+	}
+	assert.NotNil(t, store.PruneSnapshots(ctx, id, "State", 0))
+	assert.Nil(t, store.PruneSnapshots(ctx, id, "State", 2))
+	//nothing to prune
+	assert.Nil(t, store.PruneSnapshots(ctx, id, "State", 2))
+	assert.Nil(t, store.PruneSnapshots(ctx, id, "Token", 2))
+
+	//the latest snapshots are kept
+	client, err := mongo_driver.Connect(ctx, options.Client().ApplyURI(storage.Uri))
+	assert.Nil(t, err)
+	defer client.Disconnect(ctx)
+	cursor, err := client.Database(storage.DbName).Collection("snapshot").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"takenat": 1}))
+	assert.Nil(t, err)
+	var kept []*app.PropertySnapshot
+	assert.Nil(t, cursor.All(ctx, &kept))
+	assert.Equal(t, 2, len(kept))
+	assert.Equal(t, start.Add(2*time.Minute), kept[0].TakenAt.UTC())
+	assert.Equal(t, id, kept[1].Item)
+}
@@ -0,0 +1,317 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"purrproof/smartcrawl/evm"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
+	"purrproof/smartcrawl/zilliqa"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zilliqa provider of stub node, default batch size if batchSize is 0, retries are fast
+func newZilliqaProvider(url string, batchSize int) *zilliqa.ZilliqaBlockchain {
+	provider, err := zilliqa.NewZilliqaBlockchain(&zilliqa.ZilliqaConfig{
+		Id:      "zilliqa",
+		ChainId: "1",
+		Api: &zilliqa.ZilliqaApiConfig{
+			HttpUrl:   url,
+			BatchSize: batchSize,
+			Retry:     &helpers.RetryConfig{DelayMs: 1},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return provider
+}
+
+/*
+Zilliqa node stub: every block has deploys contract creations.
+Single and batch requests are supported, latency is added to each HTTP request.
+failures holds number of calls of txid which fail with transient error before success.
+*/
+type stubNode struct {
+	deploys  int
+	latency  time.Duration
+	mu       sync.Mutex
+	requests int
+	failures map[string]int
+	//incremented by each GetSmartContractState call
+	stateVersion int
+	//deploy transactions failed by receipt
+	failedDeploys int
+	//contracts referenced by receipt of contract call in every block
+	referenced []string
+	//address => creation block, other addresses aren't contracts; token init is returned if it's nil
+	creations map[string]string
+	//calls of contract in every block, every second one fails, successful ones emit event; payment to user account is added too
+	calls int
+}
+
+// contract called by calls of stubNode
+const stubCalledContract = "5555555555555555555555555555555555555555"
+
+// public key of transactions sender, its address is 9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a
+const stubSenderPubKey = "0246E7178DC8253201101E18FD6F6EB9972451D121FC57AA2A06DD5C111E58DC6A"
+
+func (n *stubNode) respond(req jsonrpc.Request) map[string]interface{} {
+	resp := map[string]interface{}{"id": req.Id, "jsonrpc": "2.0"}
+	params, _ := req.Params.([]interface{})
+	switch req.Method {
+	case "GetTxnBodiesForTxBlock":
+		txs := make([]map[string]interface{}, 0)
+		for i := 0; i < n.deploys; i++ {
+			txs = append(txs, map[string]interface{}{
+				"ID":           fmt.Sprintf("tx%d", i),
+				"toAddr":       "0000000000000000000000000000000000000000",
+				"code":         "scilla_version 0",
+				"senderPubKey": stubSenderPubKey,
+				"receipt":      map[string]interface{}{"success": true},
+			})
+		}
+		for i := 0; i < n.failedDeploys; i++ {
+			txs = append(txs, map[string]interface{}{
+				"ID":           fmt.Sprintf("ftx%d", i),
+				"toAddr":       "0000000000000000000000000000000000000000",
+				"code":         "scilla_version 0 (* failed *)",
+				"senderPubKey": stubSenderPubKey,
+				"receipt":      map[string]interface{}{"success": false},
+			})
+		}
+		if len(n.referenced) > 0 {
+			transitions := make([]map[string]interface{}, 0)
+			logs := make([]map[string]interface{}, 0)
+			for _, address := range n.referenced {
+				transitions = append(transitions, map[string]interface{}{"addr": address, "depth": 1})
+				logs = append(logs, map[string]interface{}{"address": address, "_eventname": "Created"})
+			}
+			txs = append(txs, map[string]interface{}{
+				"ID":           "calltx",
+				"toAddr":       "4baf5fada8e5db92c3d3242618c5b47133ae003c",
+				"senderPubKey": stubSenderPubKey,
+				"receipt":      map[string]interface{}{"success": true, "transitions": transitions, "event_logs": logs},
+			})
+		}
+		for i := 0; i < n.calls; i++ {
+			receipt := map[string]interface{}{"success": i%2 == 0, "cumulative_gas": "517"}
+			if i%2 == 0 {
+				receipt["event_logs"] = []map[string]interface{}{{
+					"address":    "0x" + stubCalledContract,
+					"_eventname": "TransferSuccess",
+					"params": []map[string]interface{}{
+						{"vname": "sender", "type": "ByStr20", "value": "0x9bfec715a6bd658fcb62b0f8cc9bfa2ade71434a"},
+						{"vname": "amount", "type": "Uint128", "value": "10"},
+					},
+				}}
+			}
+			txs = append(txs, map[string]interface{}{
+				"ID":           fmt.Sprintf("ctx%d", i),
+				"amount":       "0",
+				"toAddr":       stubCalledContract,
+				"data":         `{"_tag":"Transfer","params":[]}`,
+				"senderPubKey": stubSenderPubKey,
+				"receipt":      receipt,
+			})
+		}
+		if n.calls > 0 {
+			txs = append(txs, map[string]interface{}{
+				"ID":           "paytx",
+				"amount":       "1000000000000",
+				"toAddr":       "6666666666666666666666666666666666666666",
+				"senderPubKey": stubSenderPubKey,
+				"receipt":      map[string]interface{}{"success": true, "cumulative_gas": "50"},
+			})
+		}
+		resp["result"] = txs
+	case "GetTxBlock":
+		resp["result"] = map[string]interface{}{"header": map[string]interface{}{"Timestamp": "1600000000000000"}}
+	case "GetContractAddressFromTransactionID":
+		txid, _ := params[0].(string)
+		n.mu.Lock()
+		failures := n.failures[txid]
+		if failures > 0 {
+			n.failures[txid] = failures - 1
+		}
+		n.mu.Unlock()
+		if failures > 0 {
+			resp["error"] = map[string]interface{}{"code": -20, "message": "database error"}
+		} else {
+			resp["result"] = "addr_" + txid
+		}
+	case "GetSmartContractSubState":
+		address, _ := params[0].(string)
+		field, _ := params[1].(string)
+		if len(address) != 40 {
+			resp["error"] = map[string]interface{}{"code": -8, "message": "Address size not appropriate"}
+		} else if field == "missing" {
+			resp["result"] = map[string]interface{}{}
+		} else if field == "total_supply" {
+			resp["result"] = map[string]interface{}{field: "1000"}
+		} else {
+			resp["result"] = map[string]interface{}{field: map[string]interface{}{"0x" + address: "100"}}
+		}
+	case "GetSmartContractInit":
+		address, _ := params[0].(string)
+		if n.creations != nil {
+			if block, found := n.creations[address]; found {
+				resp["result"] = []map[string]interface{}{{"vname": "_creation_block", "type": "BNum", "value": block}}
+			} else {
+				resp["error"] = map[string]interface{}{"code": -5, "message": "Address not contract address"}
+			}
+			break
+		}
+		resp["result"] = []map[string]interface{}{
+			{"vname": "_scilla_version", "type": "Uint32", "value": "0"},
+			{"vname": "name", "type": "String", "value": "Test Token"},
+			{"vname": "symbol", "type": "String", "value": "TST"},
+			{"vname": "decimals", "type": "Uint32", "value": "12"},
+		}
+	case "GetSmartContractCode":
+		resp["result"] = map[string]interface{}{"code": "scilla_version 0 contract Internal()"}
+	case "GetNumTxBlocks":
+		resp["result"] = "101"
+	case "GetSmartContractState":
+		n.mu.Lock()
+		n.stateVersion++
+		version := n.stateVersion
+		n.mu.Unlock()
+		resp["result"] = map[string]interface{}{
+			"_balance":     "0",
+			"total_supply": strconv.Itoa(1000 * version),
+			"balances":     map[string]interface{}{"0x4baf5fada8e5db92c3d3242618c5b47133ae003c": "100"},
+		}
+	default:
+		resp["error"] = map[string]interface{}{"code": helpers.RpcMethodNotFound, "message": "Method not found"}
+	}
+	return resp
+}
+
+func (n *stubNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	n.mu.Lock()
+	n.requests++
+	n.mu.Unlock()
+	time.Sleep(n.latency)
+
+	var batch []jsonrpc.Request
+	if err := json.Unmarshal(body, &batch); err == nil {
+		result := make([]map[string]interface{}, 0, len(batch))
+		for _, req := range batch {
+			result = append(result, n.respond(req))
+		}
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	var req jsonrpc.Request
+	json.Unmarshal(body, &req)
+	json.NewEncoder(w).Encode(n.respond(req))
+}
+
+func (n *stubNode) getRequests() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.requests
+}
+
+// node which doesn't respond until client gives up
+func newSlowNode() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//request context is cancelled on disconnect only after body is read
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+}
+
+// node counting calls, responds with status or with result if status is 200
+func newCountingNode(calls *int32, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "jsonrpc": "2.0", "result": "10"})
+	}))
+}
+
+// zilliqa node responding with JSON-RPC error to every call
+func newErrorNode(code int, message string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      1,
+			"jsonrpc": "2.0",
+			"error":   map[string]interface{}{"code": code, "message": message},
+		})
+	}))
+}
+
+const (
+	evmContractAddr = "0x5fbdb2315678afecb367f032d93f642f64180aa3"
+	evmDeployTxid   = "0xdeploy"
+	evmCreator      = "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266"
+	evmBytecode     = "0x6080604052"
+	//keccak256 of evmBytecode
+	evmCodeHash = "0x1c3374235d773b2189aed115aa13143020fcdbbe86e38f358cf3e4771b2f0244"
+)
+
+func newEvmProvider(url string) *evm.EvmBlockchain {
+	return evm.NewEvmBlockchain(&evm.EvmConfig{Id: "Ethereum", ChainId: "1", Api: &evm.EvmApiConfig{HttpUrl: url}})
+}
+
+// receipt of successful deploy in post-Byzantium block
+func newEvmReceipt() map[string]interface{} {
+	return map[string]interface{}{
+		"transactionHash": evmDeployTxid,
+		"contractAddress": evmContractAddr,
+		"status":          "0x1",
+	}
+}
+
+// local stub of Ethereum JSON-RPC node with one contract deployment in block 0x10
+func newEvmStubServer(t *testing.T, receipt map[string]interface{}) *httptest.Server {
+	to := "0x70997970c51812dc3a010c7d01b50e0d17dc79c8"
+	results := map[string]interface{}{
+		"eth_blockNumber": "0x11",
+		"eth_getBlockByNumber": map[string]interface{}{
+			"number":    "0x10",
+			"timestamp": "0x6400a8c0",
+			"transactions": []map[string]interface{}{
+				{"hash": "0xtransfer", "from": evmCreator, "to": to, "input": "0x"},
+				{"hash": evmDeployTxid, "from": evmCreator, "to": nil, "input": "0x6080"},
+			},
+		},
+		"eth_getTransactionReceipt": receipt,
+		"eth_getCode":               evmBytecode,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		assert.Nil(t, err)
+
+		result, found := results[req.Method]
+		assert.True(t, found, "unexpected method %s", req.Method)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.Id,
+			"result":  result,
+		})
+	}))
+}
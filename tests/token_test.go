@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"purrproof/smartcrawl/app/job"
+	"purrproof/smartcrawl/mocks"
+	"purrproof/smartcrawl/zilliqa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_FetchTokenMetadata(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+
+	token, err := provider.FetchTokenMetadata(context.Background(), stateContract, "ZRC-2")
+	assert.Nil(t, err)
+	assert.Equal(t, "ZRC-2", token.Standard)
+	assert.Equal(t, "Test Token", token.Name)
+	assert.Equal(t, "TST", token.Symbol)
+	assert.Equal(t, uint32(12), token.Decimals)
+	assert.Equal(t, "1000", token.TotalSupply)
+	assert.Equal(t, 1, token.Holders)
+	assert.False(t, token.TakenAt.IsZero())
+}
+
+func Test_ContractTokenJob(t *testing.T) {
+	stub := &stubNode{failures: map[string]int{}}
+	node := httptest.NewServer(stub)
+	defer node.Close()
+	provider := newZilliqaProvider(node.URL, 10)
+
+	stored := provider.NewItem(stateContract)
+	stored.(*zilliqa.ZilliqaContract).Code = readTestContract(t, "fungible_token.scilla")
+	repository := &mocks.ItemRepositoryMock{}
+	repository.On("Get", mock.Anything).Return(stored, nil)
+	repository.On("Update", stored, []string{"Token"}).Return(nil)
+
+	jobmsg := job.NewMessageJobPropertySet("zilmain", stored.GetId(), "Token")
+	jobmsg.SetItemProvider(provider)
+	jobmsg.SetItemRepository(repository)
+	_, err := jobmsg.Execute(context.Background())
+	assert.Nil(t, err)
+	repository.AssertExpectations(t)
+	token := stored.(*zilliqa.ZilliqaContract).Token
+	assert.Equal(t, "ZRC-2", token.Standard)
+	assert.Equal(t, "TST", token.Symbol)
+
+	//not a token, node isn't called
+	requests := stub.getRequests()
+	contract := provider.NewItem(stateContract).(*zilliqa.ZilliqaContract)
+	contract.Code = "contract Empty()"
+	assert.Nil(t, contract.CallAutosetter(context.Background(), "Token"))
+	assert.Nil(t, contract.Token)
+	assert.Equal(t, requests, stub.getRequests())
+}
@@ -0,0 +1,40 @@
+package zilliqa
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/Zilliqa/gozilliqa-sdk/bech32"
+	"github.com/Zilliqa/gozilliqa-sdk/keytools"
+	"github.com/juju/errors"
+)
+
+// address as node API expects it: lowercase hex without 0x, input may be 0x-prefixed, bare hex or bech32 (zil1...)
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if strings.HasPrefix(strings.ToLower(address), "zil1") {
+		decoded, err := bech32.FromBech32Addr(address)
+		if err != nil {
+			return "", errors.Annotatef(err, "invalid bech32 address=%s", address)
+		}
+		address = decoded
+	}
+	address = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X"))
+	if len(address) != 40 {
+		return "", errors.Errorf("invalid address length=%d", len(address))
+	} else if _, err := hex.DecodeString(address); err != nil {
+		return "", errors.Annotatef(err, "invalid hex address=%s", address)
+	}
+	return address, nil
+}
+
+// address of account with public key (hex, compressed), lowercase hex without 0x
+func addressFromPublicKey(publicKey string) (string, error) {
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(publicKey), "0x"))
+	if err != nil {
+		return "", errors.Annotatef(err, "invalid public key=%s", publicKey)
+	} else if len(key) != 33 {
+		return "", errors.Errorf("invalid public key length=%d", len(key))
+	}
+	return keytools.GetAddressFromPublic(key), nil
+}
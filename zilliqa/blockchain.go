@@ -1,13 +1,12 @@
 package zilliqa
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"purrproof/smartcrawl/app"
 	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
 	"strconv"
 	"strings"
 	"time"
@@ -27,7 +26,16 @@ import (
 )
 
 type ZilliqaApiConfig struct {
-	HttpUrl              string
+	HttpUrl string
+	//used instead of HttpUrl to spread calls over several nodes
+	Endpoints []helpers.EndpointConfig
+	//0 disables health checks of endpoints
+	HealthCheckSec int
+	//max calls in one JSON-RPC batch request
+	BatchSize            int
+	TimeoutSec           int
+	Retry                *helpers.RetryConfig
+	CircuitBreaker       *helpers.CircuitBreakerConfig
 	TxConfrimMaxAttempts int
 	TxConfirmIntervalSec int
 }
@@ -36,52 +44,159 @@ type ZilliqaConfig struct {
 	Id      string
 	ChainId string
 	Api     *ZilliqaApiConfig
+	State   *ZilliqaStateConfig
+	//find contracts created by contracts, it costs batch request per block with contract calls
+	DiscoverInternal bool
+	//kinds of items crawled from blocks, contract only by default; e.g. ["contract", "transaction"]
+	ItemKinds []string
 }
 
 const zeroAddress = "0000000000000000000000000000000000000000"
 
+// key of block transactions in container data
+const containerTransactionsKey = "zilliqa:transactions"
+
+// calls in one batch request if Api.BatchSize isn't set
+const defaultBatchSize = 50
+
+// provider type in config.json
+const ProviderType = "zilliqa"
+
 type ZilliqaBlockchain struct {
-	Provider     *provider2.Provider
-	Config       *ZilliqaConfig
-	Wallet       *account.Wallet
-	maxAttempts  int
-	timeSleepSec time.Duration
+	//SDK provider is used for transactions and contract state
+	Provider *provider2.Provider
+	//crawling calls go through own clients, they are cancelled with context
+	Endpoints  *helpers.EndpointPool
+	Config     *ZilliqaConfig
+	Wallet     *account.Wallet
+	retry      *helpers.RetryPolicy
+	batchSize  int
+	stopChecks context.CancelFunc
 }
 
 var _ app.IItemProvider = (*ZilliqaBlockchain)(nil)
+var _ app.IRateLimitedProvider = (*ZilliqaBlockchain)(nil)
+var _ app.IMultiKindProvider = (*ZilliqaBlockchain)(nil)
+
+func init() {
+	app.RegisterItemProviderType(ProviderType, NewZilliqaBlockchainFromConfig)
+}
+
+func (c *ZilliqaConfig) Validate() error {
+	if c.Id == "" {
+		return errors.New("Id is not defined")
+	} else if c.ChainId == "" {
+		return errors.New("ChainId is not defined")
+	} else if c.Api == nil || (c.Api.HttpUrl == "" && len(c.Api.Endpoints) == 0) {
+		return errors.New("Api.HttpUrl or Api.Endpoints is not defined")
+	}
+	for i, endpoint := range c.Api.Endpoints {
+		if endpoint.HttpUrl == "" {
+			return errors.Errorf("Api.Endpoints[%d].HttpUrl is not defined", i)
+		}
+	}
+	for _, kind := range c.ItemKinds {
+		if !isItemKind(strings.ToLower(kind)) {
+			return errors.Errorf("unknown item kind=%s in ItemKinds, kinds: %s", kind, strings.Join(itemKinds, ", "))
+		}
+	}
+	return nil
+}
+
+func isItemKind(kind string) bool {
+	for _, known := range itemKinds {
+		if known == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoints if defined, HttpUrl otherwise
+func (c *ZilliqaApiConfig) GetEndpoints() []helpers.EndpointConfig {
+	if len(c.Endpoints) > 0 {
+		return c.Endpoints
+	}
+	return []helpers.EndpointConfig{{HttpUrl: c.HttpUrl, Weight: 1}}
+}
 
-func NewZilliqaBlockchain(config *ZilliqaConfig) *ZilliqaBlockchain {
-	prov := provider2.NewProvider(config.Api.HttpUrl)
-	return &ZilliqaBlockchain{
-		Provider:     prov,
-		Config:       config,
-		maxAttempts:  5,
-		timeSleepSec: 2,
+func NewZilliqaBlockchainFromConfig(pconf *app.ItemProviderConfig) (app.IItemProvider, error) {
+	zconfig := ZilliqaConfig{}
+	err := pconf.Decode(&zconfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = zconfig.Validate()
+	if err != nil {
+		return nil, errors.Annotate(err, "invalid zilliqa config")
 	}
+	return NewZilliqaBlockchain(&zconfig)
+}
+
+func NewZilliqaBlockchain(config *ZilliqaConfig) (*ZilliqaBlockchain, error) {
+	endpoints := config.Api.GetEndpoints()
+	timeout := time.Duration(config.Api.TimeoutSec) * time.Second
+	pool, err := helpers.NewEndpointPool(config.Id, endpoints, timeout, config.Api.CircuitBreaker, getErrorKind)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't create endpoint pool")
+	}
+	z := &ZilliqaBlockchain{
+		//transactions are sent to the first endpoint, nonce is tracked by node
+		Provider:  provider2.NewProvider(endpoints[0].HttpUrl),
+		Endpoints: pool,
+		Config:    config,
+		retry:     helpers.NewRetryPolicy(config.Api.Retry, getErrorKind),
+		batchSize: config.Api.BatchSize,
+	}
+	if z.batchSize <= 0 {
+		z.batchSize = defaultBatchSize
+	}
+	if config.Api.HealthCheckSec > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		z.stopChecks = cancel
+		interval := time.Duration(config.Api.HealthCheckSec) * time.Second
+		go pool.RunHealthCheck(ctx, interval, func(ctx context.Context, client *jsonrpc.Client) error {
+			return client.Call(ctx, "GetNumTxBlocks", nil)
+		})
+	}
+	return z, nil
 }
 
 func (z *ZilliqaBlockchain) PrepareItemsArray(limit uint) []app.IItem {
 	result := make([]app.IItem, limit)
 	for i := uint(0); i < limit; i++ {
-		contract := &ZilliqaContract{}
+		contract := &ZilliqaContract{blockchain: z}
 		contract.Item = app.NewItem(z.Config.Id, z.Config.ChainId, "")
 		result[i] = contract
 	}
 	return result
 }
 
+// id is address in any form, valid address is converted to canonical form (see ZilliqaContract.NormalizeId)
 func (z *ZilliqaBlockchain) NewItem(id string) app.IItem {
-	contract := &ZilliqaContract{}
+	if address, err := normalizeAddress(id); err == nil {
+		id = address
+	}
+	contract := &ZilliqaContract{blockchain: z}
 	contract.Item = app.NewItem(z.Config.Id, z.Config.ChainId, id)
 	contract.RegisterAutosetters()
 	return contract
 }
 
+// limit is applied to crawling calls and BatchSubState, SDK calls (deploy, transactions) aren't limited
+func (z *ZilliqaBlockchain) SetRateLimiter(limiter app.IRateLimiter) {
+	z.Endpoints.SetRateLimiter(limiter)
+}
+
 func (z *ZilliqaBlockchain) Close() error {
+	if z.stopChecks != nil {
+		z.stopChecks()
+	}
+	z.Endpoints.LogStats()
 	return nil
 }
 
-func (z *ZilliqaBlockchain) GetContainersList(blocksNumber uint, startAfter *app.ItemsContainer) ([]*app.ItemsContainer, error) {
+func (z *ZilliqaBlockchain) GetContainersList(ctx context.Context, blocksNumber uint, startAfter *app.ItemsContainer) ([]*app.ItemsContainer, error) {
 	var startBlock uint
 	if blocksNumber == 0 {
 		return []*app.ItemsContainer{}, nil
@@ -91,12 +206,13 @@ func (z *ZilliqaBlockchain) GetContainersList(blocksNumber uint, startAfter *app
 		startBlock = startAfter.Uint() + 1
 	}
 
-	latestBlock, err := z.GetLatestBlockId()
+	latestBlock, err := z.GetLatestBlockId(ctx)
 	if err != nil {
 		logrus.WithError(err).Error("can't get latest block id")
 		return nil, errors.Annotate(err, "can't get latest block id")
 	} else if startBlock > latestBlock {
-		startBlock = latestBlock - 1
+		//nothing new on chain yet, follow mode polls here regularly
+		return []*app.ItemsContainer{}, nil
 	}
 
 	maxNumber := latestBlock - startBlock + 1
@@ -121,138 +237,241 @@ func (z *ZilliqaBlockchain) GetContainersList(blocksNumber uint, startAfter *app
 	return result, nil
 }
 
-func (z *ZilliqaBlockchain) FetchContainerItems(container *app.ItemsContainer) ([]app.IItem, error) {
+// items of kinds enabled by ItemKinds, contracts first
+func (z *ZilliqaBlockchain) FetchContainerItems(ctx context.Context, container *app.ItemsContainer) ([]app.IItem, error) {
 
 	idBlock := container.Uint()
 
-	var contractsDeployed []app.IItem
-
-	txArray, err := z.cycleGetTxnBodiesForTxBlock(idBlock)
+	txArray, err := z.getContainerTransactions(ctx, container)
 	if err != nil {
 		return nil, errors.Annotate(err, "can't fetch container items")
 	}
-	timestamp := uint32(0)
 
+	var items []app.IItem
+	//block timestamp is fetched once, 0 if it isn't fetched yet
+	var timestamp uint32
+	if z.isKindEnabled(KindContract) {
+		contracts, contractsTimestamp, err := z.fetchContracts(ctx, idBlock, txArray)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		items = append(items, contracts...)
+		timestamp = contractsTimestamp
+	}
+	if z.isKindEnabled(KindTransaction) {
+		transactions, err := z.fetchTransactions(ctx, idBlock, txArray, timestamp)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		items = append(items, transactions...)
+	}
+	return items, nil
+}
+
+/*
+Contracts deployed in block, also created by failed deploys and by contracts (DiscoverInternal).
+Block timestamp is returned too, it's 0 if block has no deploys.
+*/
+func (z *ZilliqaBlockchain) fetchContracts(ctx context.Context, idBlock uint, txArray []core.Transaction) ([]app.IItem, uint32, error) {
+
+	var contractsDeployed []app.IItem
+
+	deploys := make([]core.Transaction, 0)
+	//addresses referenced by receipts, with the first transaction referencing them
+	referenced := make([]*creationCandidate, 0)
+	seen := make(map[string]bool)
 	for _, coreTx := range txArray {
+		if z.IsContractCreation(coreTx) || z.isFailedDeploy(coreTx) {
+			deploys = append(deploys, coreTx)
+		} else if z.Config.DiscoverInternal && coreTx.Receipt.Success {
+			for _, address := range receiptAddresses(coreTx.Receipt) {
+				if !seen[address] {
+					seen[address] = true
+					referenced = append(referenced, &creationCandidate{address: address, tx: coreTx, internal: true})
+				}
+			}
+		}
+	}
+	if len(deploys) == 0 && len(referenced) == 0 {
+		return contractsDeployed, 0, nil
+	}
 
-		if !z.IsContractCreation(coreTx) {
+	//block timestamp and contract addresses are fetched by batch requests
+	txBlock := core.TxBlock{}
+	elems := []*jsonrpc.BatchElem{
+		{Method: "GetTxBlock", Params: []interface{}{strconv.Itoa(int(idBlock))}, Result: &txBlock},
+	}
+	addresses := make([]string, len(deploys))
+	for i, coreTx := range deploys {
+		elems = append(elems, &jsonrpc.BatchElem{
+			Method: "GetContractAddressFromTransactionID",
+			Params: []interface{}{coreTx.ID},
+			Result: &addresses[i],
+		})
+	}
+	err := z.batch(ctx, logrus.Fields{"block_id": idBlock}, elems)
+	if err != nil {
+		return nil, 0, errors.Annotatef(err, "can't fetch deployments of block=%d", idBlock)
+	} else if elems[0].Error != nil {
+		return nil, 0, errors.Annotatef(classifyError(elems[0].Error), "can't get timestamp for block=%d", idBlock)
+	}
+	timestamp, err := parseBlockTimestamp(&txBlock)
+	if err != nil {
+		return nil, 0, errors.Annotatef(err, "can't get timestamp for block=%d", idBlock)
+	}
+
+	candidates := make([]*creationCandidate, 0)
+	known := make(map[string]bool, len(deploys))
+	for i, coreTx := range deploys {
+		err := elems[i+1].Error
+		if err != nil && z.IsContractCreation(coreTx) {
+			return nil, 0, errors.Annotatef(classifyError(err), "can't get contract address, txid=%s", coreTx.ID)
+		} else if err != nil {
+			//failed deploy without address
 			continue
 		}
-		if timestamp == 0 {
-			//fetch timestamp once per block
-			timestamp, err = z.getBlockTimestamp(idBlock)
-			if err != nil {
-				return nil, errors.Annotatef(err, "can't get timestamp for block=%d", idBlock)
-			}
+		if address, err := normalizeAddress(addresses[i]); err == nil {
+			known[address] = true
 		}
-		contractAddr, err := z.cycleGetContractAddressFromTransactionID(coreTx.ID)
-		if err != nil {
-			return nil, errors.Annotatef(err, "can't get contract address, txid=%s", coreTx.ID)
+		if !z.IsContractCreation(coreTx) {
+			candidates = append(candidates, &creationCandidate{address: addresses[i], tx: coreTx, code: coreTx.Code})
+			continue
 		}
 
 		//init contract object
-		contract := z.NewItem(contractAddr)
-		contract.(*ZilliqaContract).Block = idBlock
-		contract.(*ZilliqaContract).Txid = coreTx.ID
-		contract.(*ZilliqaContract).Code = coreTx.Code
-		contract.(*ZilliqaContract).Timestamp = timestamp
+		contract := z.newDeployedContract(addresses[i], idBlock, timestamp, coreTx, coreTx.Code, false)
+		contractsDeployed = append(contractsDeployed, contract)
+	}
+	for _, candidate := range referenced {
+		if !known[candidate.address] {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return contractsDeployed, timestamp, nil
+	}
 
+	confirmed, err := z.confirmCreations(ctx, idBlock, candidates)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	for _, candidate := range confirmed {
+		contract := z.newDeployedContract(candidate.address, idBlock, timestamp, candidate.tx, candidate.code, candidate.internal)
+		logrus.WithFields(logrus.Fields{
+			"block_id": idBlock,
+			"address":  candidate.address,
+			"txid":     candidate.tx.ID,
+			"internal": candidate.internal,
+		}).Info("contract created not by successful deploy is found")
 		contractsDeployed = append(contractsDeployed, contract)
 	}
 
-	return contractsDeployed, nil
+	return contractsDeployed, timestamp, nil
 }
 
-func (z *ZilliqaBlockchain) getBlockTimestamp(idBlock uint) (uint32, error) {
-	for attempt := 1; attempt < z.maxAttempts; attempt++ {
-		fields := logrus.Fields{
-			"api_call":      "GetTxBlock",
-			"block_id":      idBlock,
-			"network_error": false,
-			"attempt":       attempt,
-		}
+/*
+Calls node through retry policy, so transient errors and rate limits are retried with backoff,
+each attempt goes to the next available endpoint of pool.
+Returned error has kind attached.
+*/
+func (z *ZilliqaBlockchain) call(ctx context.Context, fields logrus.Fields, method string, result interface{}, params ...interface{}) error {
+	fields["api_call"] = method
+	err := z.retry.Do(ctx, fields, func(ctx context.Context) error {
+		return z.Endpoints.Do(ctx, func(ctx context.Context, client *jsonrpc.Client) error {
+			return client.Call(ctx, method, result, params...)
+		})
+	})
+	return classifyError(err)
+}
 
-		txBlock, err := z.Provider.GetTxBlock(strconv.Itoa(int(idBlock)))
-		if err == nil {
-			timestamp, err := strconv.Atoi(txBlock.Header.Timestamp[0:10])
+/*
+Executes calls by batches of Api.BatchSize through retry policy.
+Calls failed with transient or rate limit errors are sent again with the next attempt,
+other errors of single calls are left in BatchElem.Error for caller.
+Error is returned if batch request failed or retryable errors remain after all attempts.
+*/
+func (z *ZilliqaBlockchain) batch(ctx context.Context, fields logrus.Fields, elems []*jsonrpc.BatchElem) error {
+	fields["api_call"] = "batch"
+	for start := 0; start < len(elems); start += z.batchSize {
+		end := start + z.batchSize
+		if end > len(elems) {
+			end = len(elems)
+		}
+		pending := elems[start:end]
+		err := z.retry.Do(ctx, fields, func(ctx context.Context) error {
+			err := z.Endpoints.Do(ctx, func(ctx context.Context, client *jsonrpc.Client) error {
+				return client.Batch(ctx, pending)
+			})
 			if err != nil {
-				return uint32(0), errors.Annotatef(err, "can't get timestamp from string=", txBlock.Header.Timestamp[0:10])
+				return err
 			}
-			return uint32(timestamp), nil
-		} else if helpers.IsNetworkError(err) && attempt < z.maxAttempts {
-			fields["network_error"] = true
-			logrus.WithFields(fields).Warning(err)
-			time.Sleep(z.timeSleepSec * time.Second)
-			continue
-		} else {
-			//unexpected error
-			logrus.WithFields(fields).WithError(err).Error("can't get block timestamp")
-			return uint32(0), errors.Annotate(err, "can't get block timestamp")
+			failed := make([]*jsonrpc.BatchElem, 0)
+			var lastErr error
+			for _, elem := range pending {
+				if elem.Error != nil && getErrorKind(elem.Error).IsRetryable() {
+					failed = append(failed, elem)
+					lastErr = errors.Annotatef(elem.Error, "%d of %d calls failed, method=%s", len(failed), len(pending), elem.Method)
+				}
+			}
+			pending = failed
+			return lastErr
+		})
+		if err != nil {
+			return classifyError(err)
 		}
 	}
-	return uint32(0), errors.New("can't get block timestamp, max attempts reached")
+	return nil
 }
 
-func (z *ZilliqaBlockchain) cycleGetTxnBodiesForTxBlock(idBlock uint) ([]core.Transaction, error) {
-	for attempt := 1; attempt < z.maxAttempts; attempt++ {
-		fields := logrus.Fields{
-			"api_call":      "GetTxnBodiesForTxBlock",
-			"block_id":      idBlock,
-			"network_error": false,
-			"attempt":       attempt,
-		}
-
-		txArray, err := z.Provider.GetTxnBodiesForTxBlock(strconv.Itoa(int(idBlock)))
-		if err == nil {
-			return txArray, nil
-		} else if strings.Contains(err.Error(), "TxBlock has no transactions") ||
-			strings.Contains(err.Error(), "Txn Hash not Present") ||
-			strings.Contains(err.Error(), "Failed to get Microblock") || //block 1664279
-			strings.Contains(err.Error(), "Tx Block does not exist") {
-			logrus.WithFields(logrus.Fields{
-				"api_call": "GetTxnBodiesForTxBlock",
-				"block_id": idBlock,
-			}).Debug(err)
-			return make([]core.Transaction, 0), nil
-		} else if helpers.IsNetworkError(err) && attempt < z.maxAttempts {
-			fields["network_error"] = true
-			logrus.WithFields(fields).Warning(err)
-			time.Sleep(2 * time.Second)
-			continue
-		} else {
-			//unexpected error
-			logrus.WithFields(fields).WithError(err).Error("can't get block transactions")
-			return nil, errors.Annotate(err, "can't get block transactions")
-		}
+func (z *ZilliqaBlockchain) getBlockTimestamp(ctx context.Context, idBlock uint) (uint32, error) {
+	txBlock := core.TxBlock{}
+	err := z.call(ctx, logrus.Fields{"block_id": idBlock}, "GetTxBlock", &txBlock, strconv.Itoa(int(idBlock)))
+	if err != nil {
+		return 0, errors.Annotatef(err, "can't get timestamp for block=%d", idBlock)
 	}
-	return nil, errors.New("can't get block transactions, max attempts reached")
+	timestamp, err := parseBlockTimestamp(&txBlock)
+	return timestamp, errors.Annotatef(err, "can't get timestamp for block=%d", idBlock)
 }
 
-func (z *ZilliqaBlockchain) cycleGetContractAddressFromTransactionID(txid string) (string, error) {
-	for attempt := 1; attempt < z.maxAttempts; attempt++ {
-		fields := logrus.Fields{
-			"api_call":      "GetContractAddressFromTransactionID",
-			"txid":          txid,
-			"network_error": false,
-			"attempt":       attempt,
-		}
-
-		contractAddr, err := z.Provider.GetContractAddressFromTransactionID(txid)
+func parseBlockTimestamp(txBlock *core.TxBlock) (uint32, error) {
+	if len(txBlock.Header.Timestamp) < 10 {
+		err := errors.Errorf("unexpected timestamp=%s", txBlock.Header.Timestamp)
+		return uint32(0), app.NewClassifiedError(app.ErrorKindPermanent, err)
+	}
+	timestamp, err := strconv.Atoi(txBlock.Header.Timestamp[0:10])
+	if err != nil {
+		err = errors.Annotatef(err, "can't get timestamp from string=%s", txBlock.Header.Timestamp[0:10])
+		return uint32(0), app.NewClassifiedError(app.ErrorKindPermanent, err)
+	}
+	return uint32(timestamp), nil
+}
 
-		if err == nil {
-			return contractAddr, nil
-		} else if helpers.IsNetworkError(err) && attempt < z.maxAttempts {
-			fields["network_error"] = true
-			logrus.WithFields(fields).Warning(err)
-			time.Sleep(2 * time.Second)
-			continue
-		} else {
-			//unexpected error
-			logrus.WithFields(fields).WithError(err).Error("can't get contract address")
-			return "", errors.Annotate(err, "can't get contract address")
-		}
+// transactions of block are fetched once per container job, items, calls and events are extracted from them
+func (z *ZilliqaBlockchain) getContainerTransactions(ctx context.Context, container *app.ItemsContainer) ([]core.Transaction, error) {
+	if cached, found := container.GetData(containerTransactionsKey); found {
+		return cached.([]core.Transaction), nil
+	}
+	txArray, err := z.cycleGetTxnBodiesForTxBlock(ctx, container.Uint())
+	if err != nil {
+		return nil, errors.Trace(err)
 	}
-	return "", errors.New("can't get contract address, max attempts reached")
+	container.SetData(containerTransactionsKey, txArray)
+	return txArray, nil
+}
+
+func (z *ZilliqaBlockchain) cycleGetTxnBodiesForTxBlock(ctx context.Context, idBlock uint) ([]core.Transaction, error) {
+	fields := logrus.Fields{"block_id": idBlock}
+	var txArray []core.Transaction
+	err := z.call(ctx, fields, "GetTxnBodiesForTxBlock", &txArray, strconv.Itoa(int(idBlock)))
+	if app.GetErrorKind(err) == app.ErrorKindNotFound {
+		//block without transactions
+		logrus.WithFields(fields).Debug(err)
+		return make([]core.Transaction, 0), nil
+	} else if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("can't get block transactions")
+		return nil, errors.Annotate(err, "can't get block transactions")
+	}
+	return txArray, nil
 }
 
 func (z *ZilliqaBlockchain) IsContractCreation(txn core.Transaction) bool {
@@ -262,16 +481,24 @@ func (z *ZilliqaBlockchain) IsContractCreation(txn core.Transaction) bool {
 	return false
 }
 
-func (z *ZilliqaBlockchain) GetLatestBlockId() (uint, error) {
-	result, err := z.Provider.GetNumTxBlocks()
+func (z *ZilliqaBlockchain) GetLatestBlockId(ctx context.Context) (uint, error) {
+	result := ""
+	err := z.call(ctx, logrus.Fields{}, "GetNumTxBlocks", &result)
 	if err != nil {
 		return 0, errors.Annotate(err, "can't get blockhain height")
 	}
 	res, _ := strconv.Atoi(result)
-	return uint(res), nil
+	if res == 0 {
+		return 0, errors.New("blockhain has no blocks")
+	}
+	/*
+		GetNumTxBlocks is number of blocks and they are numbered from 0, so block with that number doesn't exist yet.
+		The latest one is number of blocks - 1, as eth_blockNumber of evm provider.
+	*/
+	return uint(res - 1), nil
 }
 
-/*func (z *ZilliqaBlockchain) RestoreContract(contractAddress string) (*app.IItem, error) {
+/*func (z *ZilliqaBlockchain) RestoreContract(ctx context.Context, contractAddress string) (*app.IItem, error) {
 	//TODO: errors.Trace/Annotate
 	////b32, err := bech32.ToBech32Address(contractAddress)
 	////if err != nil {
@@ -284,8 +511,8 @@ func (z *ZilliqaBlockchain) GetLatestBlockId() (uint, error) {
 		addr = addr[2:]
 	}
 
-	////result,err
-	_, err := z.Provider.GetSmartContractInit(addr)
+	var init []interface{}
+	err := z.call(ctx, logrus.Fields{"address": addr}, "GetSmartContractInit", &init, addr)
 	if err != nil {
 		return nil, err
 	}
@@ -308,101 +535,91 @@ func (z *ZilliqaBlockchain) SetWallet(newKey string) error {
 	return nil
 }
 
-func (z *ZilliqaBlockchain) State(contractAddress string) (string, error) {
-	//TODO: errors.Trace/Annotate
-	rsp, err := z.Provider.GetSmartContractState(contractAddress[2:])
+// address may be 0x-prefixed, bare hex or bech32
+func (z *ZilliqaBlockchain) State(ctx context.Context, contractAddress string) (string, error) {
+	address, err := normalizeAddress(contractAddress)
 	if err != nil {
-		return "", err
+		return "", app.NewClassifiedError(app.ErrorKindPermanent, errors.Annotate(err, "can't get contract state"))
 	}
-	result, err := json.MarshalIndent(rsp.Result, "", "     ")
+	var state interface{}
+	err = z.call(ctx, logrus.Fields{"address": address}, "GetSmartContractState", &state, address)
 	if err != nil {
-		return "", err
+		return "", errors.Annotatef(err, "can't get contract state, address=%s", address)
 	}
-	state := string(result)
-	return state, nil
-}
-
-func (z *ZilliqaBlockchain) SubState(contractAddress string, params ...interface{}) (string, error) {
-	//TODO: errors.Trace/Annotate
-	rsp, err := z.Provider.GetSmartContractSubState(contractAddress, params...)
+	result, err := json.MarshalIndent(state, "", "     ")
 	if err != nil {
-		return "", err
+		return "", errors.Trace(err)
 	}
-
-	state := string(rsp)
-
-	return state, nil
+	return string(result), nil
 }
 
-func (z *ZilliqaBlockchain) BuildBatchParams(fields []string) [][]interface{} {
-	//TODO: errors.Trace/Annotate
-	var params [][]interface{}
-
-	for _, v := range fields {
-		params = append(params, []interface{}{v, []string{}})
+// address may be 0x-prefixed, bare hex or bech32, params are field name and keys of map field
+func (z *ZilliqaBlockchain) SubState(ctx context.Context, contractAddress string, params ...interface{}) (string, error) {
+	address, err := normalizeAddress(contractAddress)
+	if err != nil {
+		return "", app.NewClassifiedError(app.ErrorKindPermanent, errors.Annotate(err, "can't get contract substate"))
 	}
-
-	return params
+	var state json.RawMessage
+	err = z.call(ctx, logrus.Fields{"address": address}, "GetSmartContractSubState", &state, append([]interface{}{address}, params...)...)
+	if err != nil {
+		return "", errors.Annotatef(err, "can't get contract substate, address=%s", address)
+	}
+	return string(state), nil
 }
 
-// Inspired by https://github.com/Zilliqa/gozilliqa-sdk/blob/2ff222c97fc6fa2855ef2c5bffbd56faddd6291f/provider/provider.go#L877
-func (z *ZilliqaBlockchain) BatchSubState(contractAddress string, fields []string) (string, error) {
-	//TODO: errors.Trace/Annotate
-
-	params := z.BuildBatchParams(fields)
+// value of contract field or error of this field only
+type SubStateResult struct {
+	//decoded JSON value of field
+	Value interface{}
+	Error error
+}
 
-	//we should hack here for now
-	type req struct {
-		Id      string      `json:"id"`
-		Jsonrpc string      `json:"jsonrpc"`
-		Method  string      `json:"method"`
-		Params  interface{} `json:"params"`
+/*
+Fetches fields of contract state by batch request, result is map by field name.
+Field missing in state has error of not found kind.
+Address may be 0x-prefixed, bare hex or bech32.
+*/
+func (z *ZilliqaBlockchain) BatchSubState(ctx context.Context, contractAddress string, fields []string) (map[string]*SubStateResult, error) {
+	address, err := normalizeAddress(contractAddress)
+	if err != nil {
+		return nil, app.NewClassifiedError(app.ErrorKindPermanent, errors.Annotate(err, "can't get contract substate"))
 	}
 
-	reqs := []*req{}
-
-	for i, param := range params {
-		p := []interface{}{
-			contractAddress[2:],
-		}
-
-		for _, v := range param {
-			p = append(p, v)
-		}
-
-		r := &req{
-			Id:      strconv.Itoa(i + 1),
-			Jsonrpc: "2.0",
-			Method:  "GetSmartContractSubState",
-			Params:  p,
+	elems := make([]*jsonrpc.BatchElem, len(fields))
+	states := make([]map[string]json.RawMessage, len(fields))
+	for i, field := range fields {
+		elems[i] = &jsonrpc.BatchElem{
+			Method: "GetSmartContractSubState",
+			Params: []interface{}{address, field, []string{}},
+			Result: &states[i],
 		}
-
-		reqs = append(reqs, r)
-	}
-
-	b, _ := json.Marshal(reqs)
-	reader := bytes.NewReader(b)
-	request, err := http.NewRequest("POST", z.Config.Api.HttpUrl, reader)
-	if err != nil {
-		return "", err
 	}
-	request.Header.Set("Content-Type", "application/json;charset=UTF-8")
-	client := http.Client{}
-	resp, err := client.Do(request)
+	err = z.batch(ctx, logrus.Fields{"address": address}, elems)
 	if err != nil {
-		return "", err
+		return nil, errors.Annotatef(err, "can't get contract substate, address=%s", address)
 	}
-	defer resp.Body.Close()
 
-	result, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	result := make(map[string]*SubStateResult, len(fields))
+	for i, field := range fields {
+		fieldResult := &SubStateResult{}
+		result[field] = fieldResult
+		if elems[i].Error != nil {
+			fieldResult.Error = classifyError(elems[i].Error)
+			continue
+		}
+		raw, found := states[i][field]
+		if !found {
+			err := errors.Errorf("field=%s not found in contract state", field)
+			fieldResult.Error = app.NewClassifiedError(app.ErrorKindNotFound, err)
+			continue
+		}
+		err := json.Unmarshal(raw, &fieldResult.Value)
+		if err != nil {
+			err = errors.Annotatef(err, "can't decode field=%s", field)
+			fieldResult.Error = app.NewClassifiedError(app.ErrorKindPermanent, err)
+		}
 	}
-
-	//response needs to be parsed, because error is possible
-	//[{"error":{"code":-8,"data":null,"message":"Address size not appropriate"},"id":"1","jsonrpc":"2.0"},{"error":{"code":-8,"data":null,"message":"Address size not appropriate"},"id":"2","jsonrpc":"2.0"}]
-
-	return string(result), nil
+	return result, nil
 }
 
 func (z *ZilliqaBlockchain) Deploy(code string, init []core.ContractValue) (*transaction2.Transaction, error) {
@@ -0,0 +1,88 @@
+package zilliqa
+
+import (
+	"context"
+	"encoding/json"
+	"purrproof/smartcrawl/app"
+	"strconv"
+
+	"github.com/Zilliqa/gozilliqa-sdk/core"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var _ app.IActivityProvider = (*ZilliqaBlockchain)(nil)
+
+/*
+Calls of contracts in block: transactions to non-zero address with transition tag in data.
+Payments to user accounts have no data, they are skipped.
+Transitions called by contracts (receipt transitions) aren't calls of transactions, they are skipped too.
+*/
+func (z *ZilliqaBlockchain) FetchContainerCalls(ctx context.Context, container *app.ItemsContainer) ([]*app.ItemCall, error) {
+	idBlock := container.Uint()
+	txArray, err := z.getContainerTransactions(ctx, container)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't fetch container calls")
+	}
+
+	calls := make([]*app.ItemCall, 0)
+	for _, coreTx := range txArray {
+		if coreTx.ToAddr == zeroAddress || coreTx.Code != "" {
+			continue
+		}
+		tag := getTransitionTag(coreTx.Data)
+		if tag == "" {
+			continue
+		}
+		address, err := normalizeAddress(coreTx.ToAddr)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"txid": coreTx.ID}).Warn("invalid address of called contract")
+			continue
+		}
+		calls = append(calls, z.newCall(address, idBlock, coreTx, tag))
+	}
+	return calls, nil
+}
+
+func (z *ZilliqaBlockchain) newCall(address string, idBlock uint, coreTx core.Transaction, tag string) *app.ItemCall {
+	call := &app.ItemCall{
+		Item: &app.ItemId{
+			ProvName:   z.Config.Id,
+			ProvBranch: z.Config.ChainId,
+			Id:         address,
+		},
+		Seq:     idBlock,
+		Txid:    coreTx.ID,
+		Tag:     tag,
+		Success: coreTx.Receipt.Success,
+		Amount:  coreTx.Amount,
+	}
+	call.Gas, _ = strconv.ParseUint(coreTx.Receipt.CumulativeGas, 10, 64)
+	sender, err := addressFromPublicKey(coreTx.SenderPubKey)
+	if err == nil {
+		sender, err = normalizeAddress(sender)
+	}
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"txid": coreTx.ID}).Warn("can't get sender of call")
+	} else {
+		//in the form of contract Id, so it can be matched with ids of contracts
+		call.Sender = sender
+	}
+	return call
+}
+
+// _tag of transaction data, it's JSON string or object depending on API version
+func getTransitionTag(data interface{}) string {
+	message := struct {
+		Tag string `json:"_tag"`
+	}{}
+	switch value := data.(type) {
+	case string:
+		if json.Unmarshal([]byte(value), &message) != nil {
+			return ""
+		}
+	case map[string]interface{}:
+		message.Tag, _ = value["_tag"].(string)
+	}
+	return message.Tag
+}
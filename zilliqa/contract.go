@@ -1,8 +1,18 @@
 package zilliqa
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"purrproof/smartcrawl/app"
-	"regexp"
+	"purrproof/smartcrawl/zilliqa/scilla"
+	"sort"
+	"strings"
+
+	"github.com/Zilliqa/gozilliqa-sdk/bech32"
+	"github.com/Zilliqa/gozilliqa-sdk/util"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type ZilliqaContract struct {
@@ -15,58 +25,273 @@ type ZilliqaContract struct {
 	Txid      string `bson:"txid"`
 	Code      string `bson:"code"`
 	Timestamp uint32 `bson:"timestamp"`
+	//other forms of Id (lowercase hex without 0x): zil1... and 0x-prefixed checksummed hex
+	AddressBech32   string `bson:"addressbech32"`
+	AddressChecksum string `bson:"addresschecksum"`
+	//sender of deploy transaction in the form of Id (lowercase hex without 0x) and bech32
+	Creator       string `bson:"creator"`
+	CreatorBech32 string `bson:"creatorbech32"`
+	//created by another contract, Creator is sender of transaction which called it
+	Internal bool `bson:"internal"`
+	//contract called by transaction which created internal contract, in the form of Id
+	CreatorContract string `bson:"creatorcontract,omitempty"`
 	//realtime computed properties
 	Name      string `bson:"name"`
 	Library   string `bson:"library"`
 	SizeBytes int    `bson:"sizebytes"`
+	//sha256 of normalized code, clones have the same hash
+	CodeHash string `bson:"codehash"`
+	//parsed from code, e.g. contracts with Transfer transition: {"transitions.name": "Transfer"}
+	ScillaVersion int                `bson:"scillaversion"`
+	Imports       []string           `bson:"imports"`
+	Params        []scilla.Param     `bson:"params"`
+	Fields        []scilla.Param     `bson:"fields"`
+	Transitions   []scilla.Component `bson:"transitions"`
+	Procedures    []scilla.Component `bson:"procedures"`
+	Events        []string           `bson:"events"`
+	//ZRC standards, e.g. fungible tokens: {"standards": {"$elemMatch": {"standard": "ZRC-2", "conformant": true}}}
+	Standards []StandardConformance `bson:"standards"`
 	//delayed computed properties
 	//Test  string `bson:"test"`
+	State *ContractState `bson:"state"`
+	//metadata of token contract, nil for other contracts
+	Token *TokenMetadata `bson:"token"`
+	//aggregated calls, they are updated by call repository only (Providers.<key>.TrackCalls)
+	Calls *app.CallStats `bson:"calls,omitempty"`
+	//provider which created item, it's used by delayed properties
+	blockchain *ZilliqaBlockchain
+	//snapshots of State, it's set by job:property:set
+	snapshotStore app.ISnapshotStore
+	//parsed code, it's shared by realtime properties
+	parsed     *scilla.Contract
+	parseErr   error
+	parsedCode string
 }
 
 var _ app.IItem = (*ZilliqaContract)(nil)
+var _ app.INormalizableItem = (*ZilliqaContract)(nil)
+var _ app.ISnapshotItem = (*ZilliqaContract)(nil)
+
+func (c *ZilliqaContract) SetSnapshotStore(store app.ISnapshotStore) {
+	c.snapshotStore = store
+}
+
+// Id may be set in any form of address: hex (0x-prefixed or not, any case) or bech32
+func (c *ZilliqaContract) NormalizeId() error {
+	address, err := normalizeAddress(c.Id)
+	if err != nil {
+		return errors.Annotatef(err, "invalid contract address=%s", c.Id)
+	}
+	c.Id = address
+	return nil
+}
 
 func (c *ZilliqaContract) RegisterAutosetters() error {
 	c.RegisterRealtimeAutosetter("SizeBytes", c.AutosetSizeBytes)
+	c.RegisterRealtimeAutosetter("AddressBech32", c.AutosetAddressBech32)
+	c.RegisterRealtimeAutosetter("AddressChecksum", c.AutosetAddressChecksum)
+	c.RegisterRealtimeAutosetter("CodeHash", c.AutosetCodeHash)
 	c.RegisterRealtimeAutosetter("Name", c.AutosetName)
 	c.RegisterRealtimeAutosetter("Library", c.AutosetLibrary)
+	c.RegisterRealtimeAutosetter("ScillaVersion", c.AutosetScillaVersion)
+	c.RegisterRealtimeAutosetter("Imports", c.AutosetImports)
+	c.RegisterRealtimeAutosetter("Params", c.AutosetParams)
+	c.RegisterRealtimeAutosetter("Fields", c.AutosetFields)
+	c.RegisterRealtimeAutosetter("Transitions", c.AutosetTransitions)
+	c.RegisterRealtimeAutosetter("Procedures", c.AutosetProcedures)
+	c.RegisterRealtimeAutosetter("Events", c.AutosetEvents)
+	c.RegisterRealtimeAutosetter("Standards", c.AutosetStandards)
 	//c.RegisterDelayedAutosetter("Test", c.AutosetTest)
+	c.RegisterDelayedAutosetter("State", c.AutosetState)
+	c.RegisterDelayedAutosetter("Token", c.AutosetToken)
 	return nil
 }
 
 /* ========== realtime computed properties ========== */
 
-func (c *ZilliqaContract) AutosetSizeBytes() error {
+func (c *ZilliqaContract) AutosetSizeBytes(ctx context.Context) error {
 	size := len(c.Code)
 	c.SizeBytes = size
 	return nil
 }
 
-//contract name
-func (c *ZilliqaContract) AutosetName() error {
-	re := regexp.MustCompilePOSIX(`contract[ \n\r\t]*([A-Za-z0-9_]+)[ \n\r\t]*\(`)
-	result := re.FindStringSubmatch(c.Code)
-	if len(result) > 0 {
-		c.Name = result[1]
-	} else {
-		c.Name = ""
+func (c *ZilliqaContract) AutosetAddressBech32(ctx context.Context) error {
+	address, err := normalizeAddress(c.Id)
+	if err != nil {
+		return errors.Annotatef(err, "invalid contract address=%s", c.Id)
 	}
+	c.AddressBech32, err = bech32.ToBech32Address(address)
+	return errors.Annotatef(err, "can't encode address=%s", address)
+}
+
+func (c *ZilliqaContract) AutosetAddressChecksum(ctx context.Context) error {
+	address, err := normalizeAddress(c.Id)
+	if err != nil {
+		return errors.Annotatef(err, "invalid contract address=%s", c.Id)
+	}
+	c.AddressChecksum = util.ToCheckSumAddress(address)
+	return nil
+}
+
+// hash doesn't depend on comments and formatting
+func (c *ZilliqaContract) AutosetCodeHash(ctx context.Context) error {
+	code, err := scilla.Normalize(c.Code)
+	if err != nil {
+		//malformed code, whitespace is normalized only
+		code = strings.Join(strings.Fields(c.Code), " ")
+	}
+	hash := sha256.Sum256([]byte(code))
+	c.CodeHash = "0x" + hex.EncodeToString(hash[:])
 	return nil
 }
 
-func (c *ZilliqaContract) AutosetLibrary() error {
-	re := regexp.MustCompilePOSIX(`library[ \n\r\t]*([A-Za-z0-9_]+)[ \n\r\t]+`)
-	result := re.FindStringSubmatch(c.Code)
-	if len(result) > 0 {
-		c.Library = result[1]
-	} else {
-		//sometimes there is no library really, e.g. https://viewblock.io/zilliqa/address/zil1f7lwjv7suu0e908mzqdxcthpl6mn08qfgtn7tu?tab=state
-		c.Library = ""
+/*
+Parse returns structure of contract code, it's parsed once per code.
+Structure is partial for malformed code, error is returned with it and logged once.
+*/
+func (c *ZilliqaContract) Parse() (*scilla.Contract, error) {
+	if c.parsed == nil || c.parsedCode != c.Code {
+		c.parsed, c.parseErr = scilla.Parse(c.Code)
+		if c.parseErr != nil {
+			logrus.WithField("item_id", c.GetId().String()).WithError(c.parseErr).Warning("contract code is parsed partially")
+		}
+		c.parsedCode = c.Code
 	}
+	return c.parsed, c.parseErr
+}
+
+//contract name
+func (c *ZilliqaContract) AutosetName(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Name = parsed.Name
+	return nil
+}
+
+func (c *ZilliqaContract) AutosetLibrary(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	//sometimes there is no library really, e.g. https://viewblock.io/zilliqa/address/zil1f7lwjv7suu0e908mzqdxcthpl6mn08qfgtn7tu?tab=state
+	c.Library = parsed.Library
+	return nil
+}
+
+func (c *ZilliqaContract) AutosetScillaVersion(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.ScillaVersion = parsed.ScillaVersion
+	return nil
+}
+
+func (c *ZilliqaContract) AutosetImports(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Imports = parsed.Imports
+	return nil
+}
+
+//immutable contract params
+func (c *ZilliqaContract) AutosetParams(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Params = parsed.Params
+	return nil
+}
+
+//mutable fields with types
+func (c *ZilliqaContract) AutosetFields(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Fields = parsed.Fields
+	return nil
+}
+
+func (c *ZilliqaContract) AutosetTransitions(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Transitions = parsed.Transitions
+	return nil
+}
+
+func (c *ZilliqaContract) AutosetProcedures(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Procedures = parsed.Procedures
+	return nil
+}
+
+//names of emitted events
+func (c *ZilliqaContract) AutosetEvents(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Events = parsed.Events
+	return nil
+}
+
+//ZRC standards which contract conforms to, fully or partially
+func (c *ZilliqaContract) AutosetStandards(ctx context.Context) error {
+	parsed, _ := c.Parse()
+	c.Standards = DetectStandards(parsed)
 	return nil
 }
 
 /* ========== delayed computed properties ========== */
-/*func (c *ZilliqaContract) AutosetTest() error {
+
+// snapshot of mutable state is saved to snapshot store, item keeps its summary
+func (c *ZilliqaContract) AutosetState(ctx context.Context) error {
+	if c.blockchain == nil {
+		return errors.New("contract isn't created by provider")
+	} else if c.snapshotStore == nil {
+		return errors.New("snapshot store isn't set")
+	}
+	snapshot, err := c.blockchain.SnapshotState(ctx, c.Id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = c.snapshotStore.SaveSnapshot(ctx, &app.PropertySnapshot{
+		Item:     c.GetId(),
+		Property: "State",
+		TakenAt:  snapshot.TakenAt,
+		Value:    snapshot,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	historySize := c.blockchain.Config.GetStateConfig().HistorySize
+	if historySize < 0 {
+		historySize = 0
+	}
+	err = c.snapshotStore.PruneSnapshots(ctx, c.GetId(), "State", historySize+1)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	names := make([]string, 0, len(snapshot.Fields))
+	for name := range snapshot.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.State = &ContractState{
+		Height:        snapshot.Height,
+		TakenAt:       snapshot.TakenAt,
+		FieldNames:    names,
+		SkippedFields: snapshot.SkippedFields,
+	}
+	return nil
+}
+
+// name, symbol, decimals, supply and holders of ZRC-2/ZRC-6/ZRC-1 token, standards are detected by code
+func (c *ZilliqaContract) AutosetToken(ctx context.Context) error {
+	if c.blockchain == nil {
+		return errors.New("contract isn't created by provider")
+	}
+	parsed, _ := c.Parse()
+	standard := getTokenStandard(DetectStandards(parsed))
+	if standard == "" {
+		c.Token = nil
+		return nil
+	}
+	token, err := c.blockchain.FetchTokenMetadata(ctx, c.Id, standard)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.Token = token
+	return nil
+}
+
+/*func (c *ZilliqaContract) AutosetTest(ctx context.Context) error {
 	c.Test = "foo"
 	return nil
 }*/
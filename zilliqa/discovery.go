@@ -0,0 +1,143 @@
+package zilliqa
+
+import (
+	"context"
+	"purrproof/smartcrawl/jsonrpc"
+	"strconv"
+
+	"github.com/Zilliqa/gozilliqa-sdk/bech32"
+	"github.com/Zilliqa/gozilliqa-sdk/core"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+Contracts which aren't top-level successful deploys are candidates, they are confirmed by _creation_block init param:
+- deploy transactions failed by receipt (e.g. retried by node), contract may exist anyway;
+- contracts referenced by receipts of other transactions (transitions, event logs), if DiscoverInternal is enabled.
+Node API doesn't list contracts created by contracts, so internal contract is found only if receipt of
+its block references it.
+*/
+type creationCandidate struct {
+	address string
+	tx      core.Transaction
+	//empty for internal contracts, code is fetched after confirmation
+	code     string
+	internal bool
+}
+
+// deploy transaction which failed by receipt
+func (z *ZilliqaBlockchain) isFailedDeploy(txn core.Transaction) bool {
+	return txn.ToAddr == zeroAddress && !txn.Receipt.Success && txn.Code != ""
+}
+
+// contract addresses referenced by receipt, normalized
+func receiptAddresses(receipt core.TransactionReceipt) []string {
+	result := make([]string, 0)
+	add := func(address string) {
+		if normalized, err := normalizeAddress(address); err == nil {
+			result = append(result, normalized)
+		}
+	}
+	for _, transition := range receipt.Transitions {
+		add(transition.Addr)
+	}
+	for _, log := range receipt.EventLogs {
+		if fields, ok := log.(map[string]interface{}); ok {
+			if address, ok := fields["address"].(string); ok {
+				add(address)
+			}
+		}
+	}
+	return result
+}
+
+// candidates created in block, code of internal contracts is set
+func (z *ZilliqaBlockchain) confirmCreations(ctx context.Context, idBlock uint, candidates []*creationCandidate) ([]*creationCandidate, error) {
+	fields := logrus.Fields{"block_id": idBlock}
+	inits := make([][]core.ContractValue, len(candidates))
+	elems := make([]*jsonrpc.BatchElem, len(candidates))
+	for i, candidate := range candidates {
+		elems[i] = &jsonrpc.BatchElem{Method: "GetSmartContractInit", Params: []interface{}{candidate.address}, Result: &inits[i]}
+	}
+	if err := z.batch(ctx, fields, elems); err != nil {
+		return nil, errors.Annotatef(err, "can't get init of contract candidates, block=%d", idBlock)
+	}
+
+	confirmed := make([]*creationCandidate, 0)
+	for i, candidate := range candidates {
+		//error means that address isn't contract
+		if elems[i].Error != nil || getCreationBlock(inits[i]) != strconv.Itoa(int(idBlock)) {
+			continue
+		}
+		confirmed = append(confirmed, candidate)
+	}
+
+	internal := make([]*creationCandidate, 0)
+	for _, candidate := range confirmed {
+		if candidate.code == "" {
+			internal = append(internal, candidate)
+		}
+	}
+	if len(internal) == 0 {
+		return confirmed, nil
+	}
+	codes := make([]struct {
+		Code string `json:"code"`
+	}, len(internal))
+	elems = make([]*jsonrpc.BatchElem, len(internal))
+	for i, candidate := range internal {
+		elems[i] = &jsonrpc.BatchElem{Method: "GetSmartContractCode", Params: []interface{}{candidate.address}, Result: &codes[i]}
+	}
+	if err := z.batch(ctx, fields, elems); err != nil {
+		return nil, errors.Annotatef(err, "can't get code of internal contracts, block=%d", idBlock)
+	}
+	for i, candidate := range internal {
+		if err := elems[i].Error; err != nil {
+			return nil, errors.Annotatef(classifyError(err), "can't get code of contract=%s", candidate.address)
+		}
+		candidate.code = codes[i].Code
+	}
+	return confirmed, nil
+}
+
+func getCreationBlock(init []core.ContractValue) string {
+	for _, param := range init {
+		if param.VName == "_creation_block" {
+			value, _ := param.Value.(string)
+			return value
+		}
+	}
+	return ""
+}
+
+/*
+Contract with deployment info, creator is sender of transaction, it's empty if sender key is invalid.
+Internal contract records contract called by transaction too.
+*/
+func (z *ZilliqaBlockchain) newDeployedContract(address string, idBlock uint, timestamp uint32, tx core.Transaction, code string, internal bool) *ZilliqaContract {
+	contract := z.NewItem(address).(*ZilliqaContract)
+	contract.Block = idBlock
+	contract.Txid = tx.ID
+	contract.Code = code
+	contract.Timestamp = timestamp
+	contract.Internal = internal
+	if internal {
+		contract.CreatorContract, _ = normalizeAddress(tx.ToAddr)
+	}
+	creator, err := addressFromPublicKey(tx.SenderPubKey)
+	if err == nil {
+		//the same form as Id, so creator may be joined with contracts and calls
+		contract.Creator, err = normalizeAddress(creator)
+	}
+	if err == nil {
+		contract.CreatorBech32, err = bech32.ToBech32Address(creator)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"address": address,
+			"txid":    tx.ID,
+		}).WithError(err).Warning("can't get creator of contract")
+	}
+	return contract
+}
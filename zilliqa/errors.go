@@ -0,0 +1,59 @@
+package zilliqa
+
+import (
+	"purrproof/smartcrawl/app"
+	"purrproof/smartcrawl/helpers"
+	"purrproof/smartcrawl/jsonrpc"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// error codes of Zilliqa node (libServer), in addition to standard JSON-RPC codes
+const (
+	rpcMiscError           = -1
+	rpcTypeError           = -3
+	rpcInvalidAddressOrKey = -5
+	rpcInvalidParameter    = -8
+	rpcDatabaseError       = -20
+	rpcParseError          = -22
+	rpcVerifyError         = -25
+	rpcVerifyRejected      = -26
+	rpcInWarmup            = -28
+)
+
+// node returns error instead of empty result for these cases
+var notFoundMessages = []string{
+	"TxBlock has no transactions",
+	"Txn Hash not Present",
+	"Failed to get Microblock", //block 1664279
+	"Tx Block does not exist",
+}
+
+func getErrorKind(err error) app.ErrorKind {
+	var rpcErr *jsonrpc.Error
+	if app.GetErrorKind(err) != app.ErrorKindUnknown || !errors.As(err, &rpcErr) {
+		return helpers.GetErrorKind(err)
+	}
+	for _, msg := range notFoundMessages {
+		if strings.Contains(rpcErr.Message, msg) {
+			return app.ErrorKindNotFound
+		}
+	}
+	switch rpcErr.Code {
+	case rpcInWarmup, rpcDatabaseError:
+		return app.ErrorKindTransient
+	case rpcTypeError, rpcInvalidAddressOrKey, rpcInvalidParameter, rpcParseError, rpcVerifyError, rpcVerifyRejected:
+		return app.ErrorKindPermanent
+	}
+	return helpers.GetErrorKind(err)
+}
+
+// err with kind attached, see app.ClassifiedError
+func classifyError(err error) error {
+	kind := getErrorKind(err)
+	if kind == app.ErrorKindUnknown || app.GetErrorKind(err) == kind {
+		return err
+	}
+	return app.NewClassifiedError(kind, err)
+}
@@ -0,0 +1,70 @@
+package zilliqa
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var _ app.IEventProvider = (*ZilliqaBlockchain)(nil)
+
+/*
+Event logs of transaction receipts in block, params are decoded to vname => value.
+Receipt transitions (messages between contracts) aren't events and are skipped,
+event logs include events of internally called contracts.
+*/
+func (z *ZilliqaBlockchain) FetchContainerEvents(ctx context.Context, container *app.ItemsContainer) ([]*app.ItemEvent, error) {
+	idBlock := container.Uint()
+	txArray, err := z.getContainerTransactions(ctx, container)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't fetch container events")
+	}
+
+	events := make([]*app.ItemEvent, 0)
+	for _, coreTx := range txArray {
+		for i, raw := range coreTx.Receipt.EventLogs {
+			log, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rawAddress, _ := log["address"].(string)
+			address, err := normalizeAddress(rawAddress)
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"txid": coreTx.ID, "index": i}).Warn("invalid address of event")
+				continue
+			}
+			event := &app.ItemEvent{
+				Item: &app.ItemId{
+					ProvName:   z.Config.Id,
+					ProvBranch: z.Config.ChainId,
+					Id:         address,
+				},
+				Seq:    idBlock,
+				Txid:   coreTx.ID,
+				Index:  uint(i),
+				Params: decodeEventParams(log["params"]),
+			}
+			event.Name, _ = log["_eventname"].(string)
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// [{vname, type, value}] => vname => value, value is string or decoded JSON of ADT, list or map
+func decodeEventParams(raw interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	params, _ := raw.([]interface{})
+	for _, rawParam := range params {
+		param, ok := rawParam.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := param["vname"].(string); ok {
+			result[name] = param["value"]
+		}
+	}
+	return result
+}
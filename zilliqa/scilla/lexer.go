@@ -0,0 +1,128 @@
+package scilla
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+type TokenKind int
+
+const (
+	TokenIdent TokenKind = iota
+	TokenNumber
+	TokenString
+	TokenPunct
+)
+
+type Token struct {
+	Kind TokenKind
+	//string literal is unquoted, escapes are kept as is
+	Value string
+	Line  int
+}
+
+// multi-char punctuation, the longest first
+var puncts = []string{"<-&", ":=", "=>", "<-", "->"}
+
+/*
+Tokenize splits Scilla source into tokens, comments (nested (* *) ones too) and whitespace are skipped.
+Tokens read before error are returned with it.
+*/
+func Tokenize(code string) ([]Token, error) {
+	tokens := make([]Token, 0, len(code)/4)
+	line := 1
+	for i := 0; i < len(code); {
+		ch := code[i]
+		switch {
+		case ch == '\n':
+			line++
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+		case strings.HasPrefix(code[i:], "(*"):
+			end, lines, err := skipComment(code, i)
+			if err != nil {
+				return tokens, errors.Annotatef(err, "line %d", line)
+			}
+			line += lines
+			i = end
+		case ch == '"':
+			end := i + 1
+			for end < len(code) && code[end] != '"' {
+				if code[end] == '\\' {
+					end++
+				} else if code[end] == '\n' {
+					line++
+				}
+				end++
+			}
+			if end >= len(code) {
+				return tokens, errors.Errorf("unterminated string, line %d", line)
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Value: code[i+1 : end], Line: line})
+			i = end + 1
+		case isDigit(ch):
+			end := i + 1
+			for end < len(code) && isIdentChar(code[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Value: code[i:end], Line: line})
+			i = end
+		case isIdentStart(ch):
+			end := i + 1
+			for end < len(code) && isIdentChar(code[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: TokenIdent, Value: code[i:end], Line: line})
+			i = end
+		default:
+			value := code[i : i+1]
+			for _, punct := range puncts {
+				if strings.HasPrefix(code[i:], punct) {
+					value = punct
+					break
+				}
+			}
+			tokens = append(tokens, Token{Kind: TokenPunct, Value: value, Line: line})
+			i += len(value)
+		}
+	}
+	return tokens, nil
+}
+
+// returns position after comment and number of line breaks in it
+func skipComment(code string, start int) (int, int, error) {
+	depth := 0
+	lines := 0
+	for i := start; i < len(code); {
+		if strings.HasPrefix(code[i:], "(*") {
+			depth++
+			i += 2
+		} else if strings.HasPrefix(code[i:], "*)") {
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, lines, nil
+			}
+		} else {
+			if code[i] == '\n' {
+				lines++
+			}
+			i++
+		}
+	}
+	return len(code), lines, errors.New("unterminated comment")
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentChar(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch) || ch == '\''
+}
@@ -0,0 +1,33 @@
+package scilla
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+/*
+Normalize returns code without comments, tokens are separated by single space,
+so the same contract formatted or commented differently has the same normalized code.
+*/
+func Normalize(code string) (string, error) {
+	tokens, err := Tokenize(code)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	var sb strings.Builder
+	sb.Grow(len(code))
+	for i, t := range tokens {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if t.Kind == TokenString {
+			sb.WriteByte('"')
+			sb.WriteString(t.Value)
+			sb.WriteByte('"')
+		} else {
+			sb.WriteString(t.Value)
+		}
+	}
+	return sb.String(), nil
+}
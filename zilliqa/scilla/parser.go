@@ -0,0 +1,344 @@
+package scilla
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+/*
+Parser of Scilla contract structure: version, imports, library, contract params, fields,
+transitions and procedures with their signatures, emitted events.
+Expressions and statements aren't parsed, they are skipped.
+*/
+
+type Param struct {
+	Name string `bson:"name"`
+	Type string `bson:"type"`
+}
+
+// transition or procedure
+type Component struct {
+	Name   string  `bson:"name"`
+	Params []Param `bson:"params"`
+}
+
+type Contract struct {
+	ScillaVersion int
+	Imports       []string
+	Library       string
+	Name          string
+	//immutable params of contract
+	Params []Param
+	//mutable fields
+	Fields      []Param
+	Transitions []Component
+	Procedures  []Component
+	//names of events emitted by literals {_eventname : "Name"; ...}, in order of appearance
+	Events []string
+}
+
+func (c *Contract) HasTransition(name string) bool {
+	return findComponent(c.Transitions, name) != nil
+}
+
+func (c *Contract) GetTransition(name string) *Component {
+	return findComponent(c.Transitions, name)
+}
+
+func (c *Contract) GetField(name string) *Param {
+	return findParam(c.Fields, name)
+}
+
+func (c *Contract) GetParam(name string) *Param {
+	return findParam(c.Params, name)
+}
+
+func (c *Contract) HasEvent(name string) bool {
+	for _, event := range c.Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findComponent(components []Component, name string) *Component {
+	for i := range components {
+		if components[i].Name == name {
+			return &components[i]
+		}
+	}
+	return nil
+}
+
+func findParam(params []Param, name string) *Param {
+	for i := range params {
+		if params[i].Name == name {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+type parser struct {
+	tokens []Token
+	pos    int
+}
+
+/*
+Parse extracts contract structure from source.
+Error is returned for malformed code, contract contains everything parsed before the error.
+*/
+func Parse(code string) (*Contract, error) {
+	contract := &Contract{
+		Imports:     []string{},
+		Params:      []Param{},
+		Fields:      []Param{},
+		Transitions: []Component{},
+		Procedures:  []Component{},
+		Events:      []string{},
+	}
+	tokens, lexErr := Tokenize(code)
+	p := &parser{tokens: tokens}
+	contract.Events = p.events()
+	err := p.parseContract(contract)
+	if lexErr != nil {
+		return contract, errors.Trace(lexErr)
+	}
+	return contract, errors.Trace(err)
+}
+
+func (p *parser) parseContract(contract *Contract) error {
+	//header: version, imports, library
+	for p.pos < len(p.tokens) && !p.isKeyword("contract") {
+		switch {
+		case p.isKeyword("scilla_version"):
+			p.pos++
+			if p.pos < len(p.tokens) && p.tokens[p.pos].Kind == TokenNumber {
+				contract.ScillaVersion, _ = strconv.Atoi(p.tokens[p.pos].Value)
+				p.pos++
+			}
+		case p.isKeyword("import"):
+			p.pos++
+			for p.pos < len(p.tokens) && p.tokens[p.pos].Kind == TokenIdent && !isHeaderKeyword(p.tokens[p.pos].Value) {
+				contract.Imports = append(contract.Imports, p.tokens[p.pos].Value)
+				p.pos++
+				//alias: import ListUtils as LU
+				if p.isKeyword("as") {
+					p.pos += 2
+				}
+			}
+		case p.isKeyword("library") && contract.Library == "":
+			p.pos++
+			if p.pos < len(p.tokens) && p.tokens[p.pos].Kind == TokenIdent {
+				contract.Library = p.tokens[p.pos].Value
+			}
+			p.pos++
+		default:
+			//address types in library may contain contract keyword: ByStr20 with contract field ... end
+			p.skip()
+		}
+	}
+	if p.pos >= len(p.tokens) {
+		return errors.New("contract declaration not found")
+	}
+
+	//contract Name (params) [with constraint =>]
+	p.pos++
+	name, err := p.ident()
+	if err != nil {
+		return errors.Annotate(err, "invalid contract name")
+	}
+	contract.Name = name
+	contract.Params, err = p.params()
+	if err != nil {
+		return errors.Annotate(err, "invalid contract params")
+	}
+
+	//body: fields, transitions, procedures
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.isKeyword("field"):
+			p.pos++
+			field, err := p.field()
+			if err != nil {
+				return errors.Annotate(err, "invalid field")
+			}
+			contract.Fields = append(contract.Fields, *field)
+		case p.isKeyword("transition"), p.isKeyword("procedure"):
+			isTransition := p.isKeyword("transition")
+			p.pos++
+			component, err := p.component()
+			if err != nil {
+				return errors.Annotate(err, "invalid transition or procedure")
+			}
+			if isTransition {
+				contract.Transitions = append(contract.Transitions, *component)
+			} else {
+				contract.Procedures = append(contract.Procedures, *component)
+			}
+		default:
+			p.skip()
+		}
+	}
+	return nil
+}
+
+// name : Type = expression
+func (p *parser) field() (*Param, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, errors.Annotatef(err, "field=%s", name)
+	}
+	typ := p.typ(func(t Token) bool { return t.Kind == TokenPunct && t.Value == "=" })
+	return &Param{Name: name, Type: typ}, nil
+}
+
+// Name (params), body is skipped by caller
+func (p *parser) component() (*Component, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	params, err := p.params()
+	if err != nil {
+		return nil, errors.Annotatef(err, "name=%s", name)
+	}
+	return &Component{Name: name, Params: params}, nil
+}
+
+// (name : Type, name : Type)
+func (p *parser) params() ([]Param, error) {
+	params := []Param{}
+	if err := p.expect("("); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if p.isPunct(")") {
+		p.pos++
+		return params, nil
+	}
+	for p.pos < len(p.tokens) {
+		name, err := p.ident()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, errors.Annotatef(err, "param=%s", name)
+		}
+		typ := p.typ(func(t Token) bool { return t.Kind == TokenPunct && (t.Value == "," || t.Value == ")") })
+		params = append(params, Param{Name: name, Type: typ})
+		if p.isPunct(")") {
+			p.pos++
+			return params, nil
+		}
+		p.pos++
+	}
+	return nil, errors.New("unexpected end of params")
+}
+
+/*
+Reads type until stop token outside of parentheses and address types (ByStr20 with ... end).
+Type is normalized: tokens separated by single spaces, no spaces inside parentheses.
+*/
+func (p *parser) typ(stop func(t Token) bool) string {
+	var sb strings.Builder
+	parens := 0
+	withs := 0
+	for ; p.pos < len(p.tokens); p.pos++ {
+		t := p.tokens[p.pos]
+		if parens == 0 && withs == 0 && stop(t) {
+			break
+		}
+		switch {
+		case t.Kind == TokenPunct && t.Value == "(":
+			parens++
+		case t.Kind == TokenPunct && t.Value == ")":
+			parens--
+		case t.Kind == TokenIdent && t.Value == "with":
+			withs++
+		case t.Kind == TokenIdent && t.Value == "end" && withs > 0:
+			withs--
+		}
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "(") && !(t.Kind == TokenPunct && (t.Value == ")" || t.Value == "," || t.Value == ":")) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t.Value)
+	}
+	return sb.String()
+}
+
+// skips token, address types are skipped entirely, so "field" inside them isn't taken as contract field
+func (p *parser) skip() {
+	if p.isKeyword("with") && p.pos > 0 && strings.HasPrefix(p.tokens[p.pos-1].Value, "ByStr20") {
+		depth := 0
+		for ; p.pos < len(p.tokens); p.pos++ {
+			if p.isKeyword("with") {
+				depth++
+			} else if p.isKeyword("end") {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+	}
+	p.pos++
+}
+
+// names of events from message literals {_eventname : "Name"; ...}
+func (p *parser) events() []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for i := 0; i+2 < len(p.tokens); i++ {
+		if p.tokens[i].Kind == TokenIdent && p.tokens[i].Value == "_eventname" &&
+			p.tokens[i+1].Value == ":" && p.tokens[i+2].Kind == TokenString {
+			name := p.tokens[i+2].Value
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+func (p *parser) ident() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("unexpected end of code")
+	}
+	t := p.tokens[p.pos]
+	if t.Kind != TokenIdent {
+		return "", errors.Errorf("identifier expected, got %q, line %d", t.Value, t.Line)
+	}
+	p.pos++
+	return t.Value, nil
+}
+
+func (p *parser) expect(punct string) error {
+	if p.pos >= len(p.tokens) {
+		return errors.Errorf("%q expected, got end of code", punct)
+	}
+	t := p.tokens[p.pos]
+	if t.Kind != TokenPunct || t.Value != punct {
+		return errors.Errorf("%q expected, got %q, line %d", punct, t.Value, t.Line)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].Kind == TokenIdent && p.tokens[p.pos].Value == keyword
+}
+
+func (p *parser) isPunct(punct string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].Kind == TokenPunct && p.tokens[p.pos].Value == punct
+}
+
+func isHeaderKeyword(value string) bool {
+	return value == "library" || value == "contract" || value == "import" || value == "let" || value == "type"
+}
@@ -0,0 +1,198 @@
+package zilliqa
+
+import (
+	"fmt"
+	"purrproof/smartcrawl/zilliqa/scilla"
+	"strings"
+)
+
+/*
+ZRC standards detection by required members of contract: immutable params, mutable fields, transitions and events.
+Only names are compared for params and events, fields are compared with their types, transitions with names of their params.
+See https://github.com/Zilliqa/ZRC
+*/
+
+const (
+	memberParam      = "param"
+	memberField      = "field"
+	memberTransition = "transition"
+	memberEvent      = "event"
+)
+
+// standard is reported as partial if this share of its members is found at least
+const minStandardConformance = 0.5
+
+type standardMember struct {
+	Kind string
+	Name string
+	//type of field, any type if it's empty
+	Type string
+	//names of transition params
+	Params []string
+}
+
+func (m standardMember) String() string {
+	switch {
+	case m.Kind == memberTransition:
+		return fmt.Sprintf("%s %s(%s)", m.Kind, m.Name, strings.Join(m.Params, ", "))
+	case m.Type != "":
+		return fmt.Sprintf("%s %s: %s", m.Kind, m.Name, m.Type)
+	}
+	return m.Kind + " " + m.Name
+}
+
+func (m standardMember) foundIn(contract *scilla.Contract) bool {
+	switch m.Kind {
+	case memberParam:
+		return contract.GetParam(m.Name) != nil
+	case memberField:
+		field := contract.GetField(m.Name)
+		return field != nil && (m.Type == "" || field.Type == m.Type)
+	case memberTransition:
+		transition := contract.GetTransition(m.Name)
+		if transition == nil || len(transition.Params) != len(m.Params) {
+			return false
+		}
+		for i, param := range transition.Params {
+			if param.Name != m.Params[i] {
+				return false
+			}
+		}
+		return true
+	case memberEvent:
+		return contract.HasEvent(m.Name)
+	}
+	return false
+}
+
+type standard struct {
+	Name    string
+	Members []standardMember
+}
+
+func stdParam(name string) standardMember {
+	return standardMember{Kind: memberParam, Name: name}
+}
+
+func stdField(name string, typ string) standardMember {
+	return standardMember{Kind: memberField, Name: name, Type: typ}
+}
+
+func stdTransition(name string, params ...string) standardMember {
+	return standardMember{Kind: memberTransition, Name: name, Params: params}
+}
+
+func stdEvent(name string) standardMember {
+	return standardMember{Kind: memberEvent, Name: name}
+}
+
+var standards = []standard{
+	{
+		//non-fungible token, deprecated by ZRC-6
+		Name: "ZRC-1",
+		Members: []standardMember{
+			stdParam("name"), stdParam("symbol"),
+			stdField("token_owners", "Map Uint256 ByStr20"),
+			stdField("owned_token_count", "Map ByStr20 Uint256"),
+			stdField("token_approvals", "Map Uint256 ByStr20"),
+			stdField("operator_approvals", "Map ByStr20 (Map ByStr20 Bool)"),
+			stdTransition("Mint", "to", "token_uri"),
+			stdTransition("Burn", "token_id"),
+			stdTransition("SetApprove", "to", "token_id"),
+			stdTransition("SetApprovalForAll", "to"),
+			stdTransition("Transfer", "to", "token_id"),
+			stdTransition("TransferFrom", "to", "token_id"),
+			stdEvent("MintSuccess"), stdEvent("BurnSuccess"), stdEvent("TransferFromSuccess"),
+		},
+	},
+	{
+		//fungible token
+		Name: "ZRC-2",
+		Members: []standardMember{
+			stdParam("contract_owner"), stdParam("name"), stdParam("symbol"), stdParam("decimals"), stdParam("init_supply"),
+			stdField("total_supply", "Uint128"),
+			stdField("balances", "Map ByStr20 Uint128"),
+			stdField("allowances", "Map ByStr20 (Map ByStr20 Uint128)"),
+			stdTransition("IncreaseAllowance", "spender", "amount"),
+			stdTransition("DecreaseAllowance", "spender", "amount"),
+			stdTransition("Transfer", "to", "amount"),
+			stdTransition("TransferFrom", "from", "to", "amount"),
+			//events are named in past tense, unlike transitions
+			stdEvent("IncreasedAllowance"), stdEvent("DecreasedAllowance"), stdEvent("TransferSuccess"), stdEvent("TransferFromSuccess"),
+		},
+	},
+	{
+		//metatransactions: fungible token transfer by signed cheque
+		Name: "ZRC-3",
+		Members: []standardMember{
+			stdField("balances", "Map ByStr20 Uint128"),
+			stdField("void_cheques", ""),
+			stdTransition("ChequeSend", "pubkey", "to", "amount", "fee", "nonce", "signature"),
+			stdEvent("ChequeSendSuccess"),
+		},
+	},
+	{
+		//multisig wallet
+		Name: "ZRC-4",
+		Members: []standardMember{
+			stdParam("owners_list"), stdParam("required_signatures"),
+			stdField("transactions", ""),
+			stdField("signatures", ""),
+			stdField("signature_counts", ""),
+			stdTransition("SubmitTransaction", "recipient", "amount", "tag"),
+			stdTransition("SignTransaction", "transactionId"),
+			stdTransition("ExecuteTransaction", "transactionId"),
+			stdTransition("RevokeSignature", "transactionId"),
+			stdTransition("AddFunds"),
+			stdEvent("Transaction created"), stdEvent("Transaction signed"), stdEvent("Transaction executed"),
+		},
+	},
+	{
+		//non-fungible token
+		Name: "ZRC-6",
+		Members: []standardMember{
+			stdParam("initial_contract_owner"), stdParam("initial_base_uri"), stdParam("name"), stdParam("symbol"),
+			stdField("token_owners", "Map Uint256 ByStr20"),
+			stdField("balances", "Map ByStr20 Uint128"),
+			stdField("spenders", "Map Uint256 ByStr20"),
+			stdField("operators", "Map ByStr20 (Map ByStr20 Bool)"),
+			stdTransition("SetSpender", "spender", "token_id"),
+			stdTransition("AddOperator", "operator"),
+			stdTransition("RemoveOperator", "operator"),
+			stdTransition("TransferFrom", "to", "token_id"),
+			stdEvent("SetSpender"), stdEvent("AddOperator"), stdEvent("RemoveOperator"), stdEvent("TransferFrom"),
+		},
+	},
+}
+
+type StandardConformance struct {
+	//e.g. ZRC-2
+	Standard string `bson:"standard"`
+	//all required members are found
+	Conformant bool `bson:"conformant"`
+	//members of partially conformant contract which are not found, e.g. "transition TransferFrom(from, to, amount)"
+	Missing []string `bson:"missing"`
+}
+
+// standards which contract conforms to, fully or partially
+func DetectStandards(contract *scilla.Contract) []StandardConformance {
+	result := []StandardConformance{}
+	for _, std := range standards {
+		missing := []string{}
+		for _, member := range std.Members {
+			if !member.foundIn(contract) {
+				missing = append(missing, member.String())
+			}
+		}
+		found := len(std.Members) - len(missing)
+		if float64(found) < minStandardConformance*float64(len(std.Members)) {
+			continue
+		}
+		result = append(result, StandardConformance{
+			Standard:   std.Name,
+			Conformant: len(missing) == 0,
+			Missing:    missing,
+		})
+	}
+	return result
+}
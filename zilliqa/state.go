@@ -0,0 +1,163 @@
+package zilliqa
+
+import (
+	"context"
+	"encoding/json"
+	"purrproof/smartcrawl/app"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// settings of contract state snapshots, Providers.<key>.State in config.json
+type ZilliqaStateConfig struct {
+	//fields of state to capture, whole mutable state if empty
+	Fields []string
+	//number of previous snapshots kept in snapshot store, defaultStateHistorySize if 0, -1 disables history
+	HistorySize int
+	//max JSON size of captured fields in bytes, defaultStateMaxSize if 0
+	MaxSize int
+}
+
+const defaultStateHistorySize = 10
+
+/*
+Snapshot is a single MongoDB document, which is limited to 16MB.
+Big maps (balances, allowances of popular tokens) don't fit, so they are skipped.
+*/
+const defaultStateMaxSize = 8 * 1024 * 1024
+
+// mutable state of contract at some moment
+type StateSnapshot struct {
+	//state is taken at this block height or a bit later
+	Height  uint                   `bson:"height"`
+	TakenAt time.Time              `bson:"takenat"`
+	Fields  map[string]interface{} `bson:"fields"`
+	//fields which don't fit into MaxSize, sorted
+	SkippedFields []string `bson:"skippedfields"`
+}
+
+/*
+Summary of the latest snapshot, snapshots are in app.ISnapshotStore (property "State"),
+so contract document doesn't grow with state of big contracts.
+*/
+type ContractState struct {
+	Height  uint      `bson:"height"`
+	TakenAt time.Time `bson:"takenat"`
+	//names of captured fields, sorted
+	FieldNames []string `bson:"fieldnames"`
+	//names of fields skipped because of size, sorted
+	SkippedFields []string `bson:"skippedfields"`
+}
+
+func (c *ZilliqaConfig) GetStateConfig() *ZilliqaStateConfig {
+	result := &ZilliqaStateConfig{}
+	if c.State != nil {
+		*result = *c.State
+	}
+	if result.HistorySize == 0 {
+		result.HistorySize = defaultStateHistorySize
+	}
+	if result.MaxSize <= 0 {
+		result.MaxSize = defaultStateMaxSize
+	}
+	return result
+}
+
+/*
+Captures state of contract: fields from Providers.<key>.State.Fields or the whole state.
+Fields missing in state are skipped.
+Fields are taken from the smallest one while their total JSON size fits into State.MaxSize, the rest are listed in SkippedFields.
+*/
+func (z *ZilliqaBlockchain) SnapshotState(ctx context.Context, contractAddress string) (*StateSnapshot, error) {
+	address, err := normalizeAddress(contractAddress)
+	if err != nil {
+		return nil, app.NewClassifiedError(app.ErrorKindPermanent, errors.Annotate(err, "can't snapshot state"))
+	}
+	height, err := z.GetLatestBlockId(ctx)
+	if err != nil {
+		return nil, errors.Annotate(err, "can't get height for state snapshot")
+	}
+	snapshot := &StateSnapshot{
+		Height:  height,
+		TakenAt: time.Now().UTC(),
+	}
+
+	config := z.Config.GetStateConfig()
+	fields := config.Fields
+	if len(fields) == 0 {
+		var state map[string]json.RawMessage
+		err := z.call(ctx, logrus.Fields{"address": address}, "GetSmartContractState", &state, address)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't get contract state, address=%s", address)
+		}
+		sizes := make(map[string]int, len(state))
+		for field, raw := range state {
+			sizes[field] = len(raw)
+		}
+		snapshot.Fields = make(map[string]interface{}, len(state))
+		for _, field := range snapshot.limitFields(sizes, config.MaxSize) {
+			var value interface{}
+			if err := json.Unmarshal(state[field], &value); err != nil {
+				err = errors.Annotatef(err, "can't decode field=%s", field)
+				return nil, app.NewClassifiedError(app.ErrorKindPermanent, err)
+			}
+			snapshot.Fields[field] = value
+		}
+		return snapshot, nil
+	}
+
+	substate, err := z.BatchSubState(ctx, address, fields)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sizes := make(map[string]int, len(fields))
+	for field, result := range substate {
+		if app.GetErrorKind(result.Error) == app.ErrorKindNotFound {
+			continue
+		} else if result.Error != nil {
+			return nil, errors.Annotatef(result.Error, "can't get field=%s, address=%s", field, address)
+		}
+		raw, err := json.Marshal(result.Value)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can't encode field=%s", field)
+		}
+		sizes[field] = len(raw)
+	}
+	snapshot.Fields = make(map[string]interface{}, len(sizes))
+	for _, field := range snapshot.limitFields(sizes, config.MaxSize) {
+		snapshot.Fields[field] = substate[field].Value
+	}
+	return snapshot, nil
+}
+
+// returns fields fitting into maxSize from the smallest one, the rest are added to SkippedFields
+func (s *StateSnapshot) limitFields(sizes map[string]int, maxSize int) []string {
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if sizes[names[i]] != sizes[names[j]] {
+			return sizes[names[i]] < sizes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	total := 0
+	for i, name := range names {
+		total += sizes[name]
+		if total > maxSize {
+			s.SkippedFields = append([]string{}, names[i:]...)
+			sort.Strings(s.SkippedFields)
+			logrus.WithFields(logrus.Fields{
+				"skipped":  s.SkippedFields,
+				"max_size": maxSize,
+			}).Warn("state snapshot is too big, fields skipped")
+			return names[:i]
+		}
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package zilliqa
+
+import (
+	"context"
+	"math/big"
+	"purrproof/smartcrawl/app"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// token standards in order of preference, when contract conforms to several of them
+var tokenStandards = []string{"ZRC-2", "ZRC-6", "ZRC-1"}
+
+// fully conformant token standard is preferred to partially conformant one, "" if contract isn't token
+func getTokenStandard(standards []StandardConformance) string {
+	for _, conformant := range []bool{true, false} {
+		for _, tokenStandard := range tokenStandards {
+			for _, detected := range standards {
+				if detected.Standard == tokenStandard && detected.Conformant == conformant {
+					return tokenStandard
+				}
+			}
+		}
+	}
+	return ""
+}
+
+/*
+Token metadata from init params and state of token contract.
+Supply and holders change, so it's refreshed by queue-property-refresh (TakenAt).
+*/
+type TokenMetadata struct {
+	//ZRC-2, ZRC-6 or ZRC-1
+	Standard string `bson:"standard"`
+	Name     string `bson:"name"`
+	Symbol   string `bson:"symbol"`
+	//0 for NFT
+	Decimals uint32 `bson:"decimals"`
+	//decimal string, Uint128 doesn't fit int64; number of tokens for ZRC-1
+	TotalSupply string `bson:"totalsupply"`
+	//addresses with non-zero balance
+	Holders int       `bson:"holders"`
+	TakenAt time.Time `bson:"takenat"`
+}
+
+// vname => value of contract init params
+func (z *ZilliqaBlockchain) GetContractInit(ctx context.Context, contractAddress string) (map[string]interface{}, error) {
+	address, err := normalizeAddress(contractAddress)
+	if err != nil {
+		return nil, app.NewClassifiedError(app.ErrorKindPermanent, errors.Annotate(err, "can't get contract init"))
+	}
+	var params []struct {
+		VName string      `json:"vname"`
+		Type  string      `json:"type"`
+		Value interface{} `json:"value"`
+	}
+	err = z.call(ctx, logrus.Fields{"address": address}, "GetSmartContractInit", &params, address)
+	if err != nil {
+		return nil, errors.Annotatef(err, "can't get contract init, address=%s", address)
+	}
+	result := make(map[string]interface{}, len(params))
+	for _, param := range params {
+		result[param.VName] = param.Value
+	}
+	return result, nil
+}
+
+// fetches metadata of token which conforms to standard (fully or partially)
+func (z *ZilliqaBlockchain) FetchTokenMetadata(ctx context.Context, contractAddress string, standard string) (*TokenMetadata, error) {
+	initParams, err := z.GetContractInit(ctx, contractAddress)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	token := &TokenMetadata{
+		Standard: standard,
+		TakenAt:  time.Now().UTC(),
+	}
+	token.Name, _ = initParams["name"].(string)
+	token.Symbol, _ = initParams["symbol"].(string)
+	if decimals, ok := initParams["decimals"].(string); ok {
+		value, err := strconv.ParseUint(decimals, 10, 32)
+		if err != nil {
+			err = errors.Annotatef(err, "invalid decimals=%s, address=%s", decimals, contractAddress)
+			return nil, app.NewClassifiedError(app.ErrorKindPermanent, err)
+		}
+		token.Decimals = uint32(value)
+	}
+
+	supplyField, holdersField := "total_supply", "balances"
+	if standard == "ZRC-1" {
+		supplyField, holdersField = "token_owners", "owned_token_count"
+	}
+	substate, err := z.BatchSubState(ctx, contractAddress, []string{supplyField, holdersField})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	values := make(map[string]interface{}, len(substate))
+	for field, result := range substate {
+		if app.GetErrorKind(result.Error) == app.ErrorKindNotFound {
+			continue
+		} else if result.Error != nil {
+			return nil, errors.Annotatef(result.Error, "can't get field=%s, address=%s", field, contractAddress)
+		}
+		values[field] = result.Value
+	}
+
+	switch supply := values[supplyField].(type) {
+	case string:
+		value, ok := new(big.Int).SetString(supply, 10)
+		if !ok {
+			err := errors.Errorf("invalid %s=%s, address=%s", supplyField, supply, contractAddress)
+			return nil, app.NewClassifiedError(app.ErrorKindPermanent, err)
+		}
+		token.TotalSupply = value.String()
+	case map[string]interface{}:
+		//ZRC-1 token_owners: token id => owner
+		token.TotalSupply = strconv.Itoa(len(supply))
+	}
+	if balances, ok := values[holdersField].(map[string]interface{}); ok {
+		for _, balance := range balances {
+			if balance != "0" {
+				token.Holders++
+			}
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,182 @@
+package zilliqa
+
+import (
+	"context"
+	"purrproof/smartcrawl/app"
+	"strconv"
+	"strings"
+
+	"github.com/Zilliqa/gozilliqa-sdk/bech32"
+	"github.com/Zilliqa/gozilliqa-sdk/core"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// item kinds of Zilliqa provider, contracts are of default kind
+const (
+	KindContract    = app.DefaultItemKind
+	KindTransaction = "transaction"
+)
+
+var itemKinds = []string{KindContract, KindTransaction}
+
+// types of transaction
+const (
+	TxTypeDeploy  = "deploy"
+	TxTypeCall    = "call"
+	TxTypePayment = "payment"
+)
+
+type ZilliqaTransaction struct {
+	*app.Item `bson:"inline"`
+	//from app.Item:
+	//Id                  string //Txid, lowercase hex without 0x
+	Block     uint   `bson:"block"`
+	Timestamp uint32 `bson:"timestamp"`
+	//deploy, call or payment
+	Type string `bson:"type"`
+	//in the form of contract Id (lowercase hex without 0x), zero address for deploy
+	Sender string `bson:"sender"`
+	To     string `bson:"to"`
+	//decimal string in Qa
+	Amount string `bson:"amount"`
+	//transition called, empty for deploy and payment
+	Tag     string `bson:"tag"`
+	Success bool   `bson:"success"`
+	Gas     uint64 `bson:"gas"`
+	//realtime computed properties
+	SenderBech32 string `bson:"senderbech32"`
+	ToBech32     string `bson:"tobech32"`
+	//provider which created item
+	blockchain *ZilliqaBlockchain
+}
+
+var _ app.IItem = (*ZilliqaTransaction)(nil)
+var _ app.IKindedItem = (*ZilliqaTransaction)(nil)
+var _ app.INormalizableItem = (*ZilliqaTransaction)(nil)
+
+func (t *ZilliqaTransaction) GetKind() string {
+	return KindTransaction
+}
+
+// Id may be 0x-prefixed and in any case
+func (t *ZilliqaTransaction) NormalizeId() error {
+	t.Id = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(t.Id, "0x"), "0X"))
+	return nil
+}
+
+func (t *ZilliqaTransaction) RegisterAutosetters() error {
+	t.RegisterRealtimeAutosetter("SenderBech32", t.AutosetSenderBech32)
+	t.RegisterRealtimeAutosetter("ToBech32", t.AutosetToBech32)
+	return nil
+}
+
+func (t *ZilliqaTransaction) AutosetSenderBech32(ctx context.Context) error {
+	var err error
+	t.SenderBech32, err = toBech32(t.Sender)
+	return errors.Annotatef(err, "txid=%s", t.Id)
+}
+
+func (t *ZilliqaTransaction) AutosetToBech32(ctx context.Context) error {
+	var err error
+	t.ToBech32, err = toBech32(t.To)
+	return errors.Annotatef(err, "txid=%s", t.Id)
+}
+
+// empty address stays empty, sender is unknown if its public key is invalid
+func toBech32(address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+	normalized, err := normalizeAddress(address)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	result, err := bech32.ToBech32Address(normalized)
+	return result, errors.Annotatef(err, "can't encode address=%s", address)
+}
+
+func (z *ZilliqaBlockchain) GetItemKinds() []string {
+	return itemKinds
+}
+
+func (z *ZilliqaBlockchain) NewItemOfKind(kind string, id string) (app.IItem, error) {
+	switch kind {
+	case KindContract:
+		return z.NewItem(id), nil
+	case KindTransaction:
+		return z.newTransaction(id), nil
+	}
+	return nil, errors.NotSupportedf("item kind=%s of zilliqa provider", kind)
+}
+
+func (z *ZilliqaBlockchain) newTransaction(id string) *ZilliqaTransaction {
+	tx := &ZilliqaTransaction{blockchain: z}
+	tx.Item = app.NewItem(z.Config.Id, z.Config.ChainId, id)
+	tx.NormalizeId()
+	tx.RegisterAutosetters()
+	return tx
+}
+
+// ItemKinds of config, contracts only by default
+func (z *ZilliqaBlockchain) isKindEnabled(kind string) bool {
+	if len(z.Config.ItemKinds) == 0 {
+		return kind == KindContract
+	}
+	for _, enabled := range z.Config.ItemKinds {
+		if strings.ToLower(enabled) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// all transactions of block, timestamp is fetched if it's 0
+func (z *ZilliqaBlockchain) fetchTransactions(ctx context.Context, idBlock uint, txArray []core.Transaction, timestamp uint32) ([]app.IItem, error) {
+	result := make([]app.IItem, 0, len(txArray))
+	if len(txArray) == 0 {
+		return result, nil
+	}
+	if timestamp == 0 {
+		var err error
+		timestamp, err = z.getBlockTimestamp(ctx, idBlock)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	for _, coreTx := range txArray {
+		tx := z.newTransaction(coreTx.ID)
+		tx.Block = idBlock
+		tx.Timestamp = timestamp
+		tx.Amount = coreTx.Amount
+		tx.Success = coreTx.Receipt.Success
+		tx.Gas, _ = strconv.ParseUint(coreTx.Receipt.CumulativeGas, 10, 64)
+		to, err := normalizeAddress(coreTx.ToAddr)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"txid": coreTx.ID}).Warn("invalid recipient of transaction")
+		} else {
+			tx.To = to
+		}
+		switch {
+		case coreTx.ToAddr == zeroAddress:
+			tx.Type = TxTypeDeploy
+		case getTransitionTag(coreTx.Data) != "":
+			tx.Type = TxTypeCall
+			tx.Tag = getTransitionTag(coreTx.Data)
+		default:
+			tx.Type = TxTypePayment
+		}
+		sender, err := addressFromPublicKey(coreTx.SenderPubKey)
+		if err == nil {
+			sender, err = normalizeAddress(sender)
+		}
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"txid": coreTx.ID}).Warn("can't get sender of transaction")
+		} else {
+			tx.Sender = sender
+		}
+		result = append(result, tx)
+	}
+	return result, nil
+}